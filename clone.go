@@ -0,0 +1,51 @@
+package dicom
+
+// Clone returns a deep copy of the element. Values that share memory, i.e.,
+// []byte, PixelDataInfo and nested *Elements, are copied, so the clone can be
+// modified without affecting the original.
+func (e *Element) Clone() *Element {
+	c := *e
+	if e.Value != nil {
+		c.Value = make([]interface{}, len(e.Value))
+		for i, v := range e.Value {
+			c.Value[i] = cloneValue(v)
+		}
+	}
+	return &c
+}
+
+func cloneValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []byte:
+		return append([]byte(nil), v...)
+	case *Element:
+		return v.Clone()
+	case PixelDataInfo:
+		c := PixelDataInfo{}
+		if v.Offsets != nil {
+			c.Offsets = append([]uint32(nil), v.Offsets...)
+		}
+		if v.Frames != nil {
+			c.Frames = make([][]byte, len(v.Frames))
+			for i, frame := range v.Frames {
+				c.Frames[i] = append([]byte(nil), frame...)
+			}
+		}
+		return c
+	}
+	// Strings, numbers and tags are immutable.
+	return v
+}
+
+// Clone returns a deep copy of the dataset. See Element.Clone.
+func (f *DataSet) Clone() *DataSet {
+	c := &DataSet{}
+	if f.Elements != nil {
+		c.Elements = make([]*Element, len(f.Elements))
+		for i, elem := range f.Elements {
+			c.Elements[i] = elem.Clone()
+		}
+	}
+	c.Reindex()
+	return c
+}
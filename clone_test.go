@@ -0,0 +1,116 @@
+package dicom_test
+
+import (
+	"testing"
+
+	"github.com/msz-kp/go-dicom"
+	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClone(t *testing.T) {
+	ds := mustReadFile(t, "examples/IM-0001-0001.dcm", dicom.ReadOptions{})
+	c := ds.Clone()
+	require.True(t, ds.Equal(c))
+
+	// Modifying the clone doesn't affect the original.
+	pixels, err := c.FindElementByTag(dicomtag.PixelData)
+	require.NoError(t, err)
+	frame := pixels.Value[0].(dicom.PixelDataInfo).Frames[0]
+	frame[0] ^= 0xff
+	assert.False(t, ds.Equal(c))
+	frame[0] ^= 0xff
+	assert.True(t, ds.Equal(c))
+
+	elem, err := c.FindElementByTag(dicomtag.PatientName)
+	require.NoError(t, err)
+	elem.Value[0] = "Doe^Jane"
+	assert.False(t, ds.Equal(c))
+	orig, err := ds.FindElementByTag(dicomtag.PatientName)
+	require.NoError(t, err)
+	assert.NotEqual(t, "Doe^Jane", orig.MustGetString())
+
+	item := dicom.MustNewElement(dicomtag.Item,
+		dicom.MustNewElement(dicomtag.ReferencedSOPInstanceUID, "1.2.3"),
+		dicom.MustNewElement(dicomtag.ReferencedSOPClassUID, "1.2"))
+	seq := dicom.MustNewElement(dicomtag.ReferencedImageSequence, item)
+	seq2 := seq.Clone()
+	require.True(t, seq.Equal(seq2))
+	seq2.Value[0].(*dicom.Element).Value[0].(*dicom.Element).Value[0] = "1.2.4"
+	assert.Equal(t, "1.2.3", item.GetElements()[0].MustGetString())
+	assert.False(t, seq.Equal(seq2))
+}
+
+func TestEqualFold(t *testing.T) {
+	newElem := func(tag dicomtag.Tag, vr string, values ...interface{}) *dicom.Element {
+		return &dicom.Element{Tag: tag, VR: vr, Value: values}
+	}
+	pairs := []struct {
+		a, b  *dicom.Element
+		equal bool
+	}{
+		{newElem(dicomtag.PatientName, "PN", "Doe^John "), newElem(dicomtag.PatientName, "PN", "Doe^John"), true},
+		{newElem(dicomtag.PatientName, "PN", "Doe^John"), newElem(dicomtag.PatientName, "PN", "Doe^Jane"), false},
+		{newElem(dicomtag.PatientName, "PN", "Doe"), newElem(dicomtag.PatientID, "LO", "Doe"), false},
+		{newElem(dicomtag.StudyID, "SH", " 1"), newElem(dicomtag.StudyID, "SH", "1"), true},
+		{newElem(dicomtag.ImageComments, "LT", " 1"), newElem(dicomtag.ImageComments, "LT", "1"), false},
+		{newElem(dicomtag.StudyInstanceUID, "UI", "1.2.3\x00"), newElem(dicomtag.StudyInstanceUID, "UI", "1.2.3"), true},
+		{newElem(dicomtag.PixelSpacing, "DS", "0.50", "1"), newElem(dicomtag.PixelSpacing, "DS", "0.5", "1.0"), true},
+		{newElem(dicomtag.PixelSpacing, "DS", "0.5"), newElem(dicomtag.PixelSpacing, "DS", float64(0.5)), true},
+		{newElem(dicomtag.PixelSpacing, "DS", "0.5"), newElem(dicomtag.PixelSpacing, "DS", "0.5", "0.5"), false},
+		{newElem(dicomtag.InstanceNumber, "IS", "+7 "), newElem(dicomtag.InstanceNumber, "IS", "7"), true},
+		{newElem(dicomtag.Rows, "US", uint16(512)), newElem(dicomtag.Rows, "US", int64(512)), true},
+		{newElem(dicomtag.Rows, "US", uint16(512)), newElem(dicomtag.Rows, "US", uint16(256)), false},
+		{newElem(dicomtag.PatientID, "LO", ""), newElem(dicomtag.PatientID, "LO"), true},
+		{newElem(dicomtag.PatientID, "LO", "1"), newElem(dicomtag.PatientID, "LO"), false},
+		{newElem(dicomtag.PixelData, "OB", []byte{1, 2, 3}), newElem(dicomtag.PixelData, "OB", []byte{1, 2, 3, 0}), true},
+		{newElem(dicomtag.PixelData, "OB", []byte{1, 2, 3}), newElem(dicomtag.PixelData, "OB", []byte{1, 2, 4}), false},
+		{
+			newElem(dicomtag.PixelData, "OB", dicom.PixelDataInfo{Offsets: []uint32{0}, Frames: [][]byte{{1, 2}}}),
+			newElem(dicomtag.PixelData, "OB", dicom.PixelDataInfo{Frames: [][]byte{{1, 2}}}),
+			true,
+		},
+		{
+			dicom.MustNewElement(dicomtag.ReferencedImageSequence, dicom.MustNewElement(dicomtag.Item,
+				dicom.MustNewElement(dicomtag.ReferencedSOPClassUID, "1.2"),
+				dicom.MustNewElement(dicomtag.ReferencedSOPInstanceUID, "1.2.3 "))),
+			dicom.MustNewElement(dicomtag.ReferencedImageSequence, dicom.MustNewElement(dicomtag.Item,
+				dicom.MustNewElement(dicomtag.ReferencedSOPInstanceUID, "1.2.3"),
+				dicom.MustNewElement(dicomtag.ReferencedSOPClassUID, "1.2"))),
+			true,
+		},
+	}
+	for _, p := range pairs {
+		assert.Equal(t, p.equal, p.a.EqualFold(p.b), "%v vs %v", p.a, p.b)
+		assert.Equal(t, p.equal, p.b.EqualFold(p.a), "%v vs %v", p.b, p.a)
+	}
+	assert.False(t, pairs[0].a.Equal(pairs[0].b))
+
+	ds := &dicom.DataSet{Elements: []*dicom.Element{pairs[0].a, pairs[3].a}}
+	ds2 := &dicom.DataSet{Elements: []*dicom.Element{pairs[3].b, pairs[0].b}}
+	assert.True(t, ds.EqualFold(ds2))
+	assert.False(t, ds.Equal(ds2))
+	ds2.Elements = ds2.Elements[1:]
+	assert.False(t, ds.EqualFold(ds2))
+
+	// Elements of duplicate tags are compared as a multiset.
+	ds = &dicom.DataSet{Elements: []*dicom.Element{pairs[0].a, pairs[1].b, pairs[3].a}}
+	ds2 = &dicom.DataSet{Elements: []*dicom.Element{pairs[3].a, pairs[1].b, pairs[0].a}}
+	assert.True(t, ds.Equal(ds2))
+	ds2.Elements[2] = pairs[1].b
+	assert.False(t, ds.Equal(ds2))
+	assert.False(t, ds2.Equal(ds))
+	ds2.Elements[2] = pairs[2].b
+	assert.False(t, ds.EqualFold(ds2))
+	assert.False(t, ds2.EqualFold(ds))
+
+	// Items are compared like datasets, regardless of element order.
+	seq := pairs[len(pairs)-1].b.Clone()
+	seq2 := seq.Clone()
+	item := seq2.Value[0].(*dicom.Element)
+	item.Value[0], item.Value[1] = item.Value[1], item.Value[0]
+	assert.True(t, seq.Equal(seq2))
+	item.Value = item.Value[:1]
+	assert.False(t, seq.Equal(seq2))
+}
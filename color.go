@@ -0,0 +1,276 @@
+package dicom
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/msz-kp/go-dicom/dicomtag"
+)
+
+// ConvertToRGB converts color pixels decoded by DecodePixels into RGB. "ds" is
+// the dataset the pixels were decoded from. It supports the following
+// photometric interpretations:
+//
+//   - RGB, which is returned as is. DecodePixels already interleaves the
+//     samples of PlanarConfiguration=1.
+//   - YBR_FULL, YBR_FULL_422 and YBR_PARTIAL_422, which are converted as
+//     defined in P3.3 C.7.6.3.1.2.
+//   - PALETTE COLOR, which is expanded through the red, green and blue
+//     palette color lookup tables, either plain or segmented. P3.3 C.7.9.
+//
+// YBR_PARTIAL_420, YBR_ICT and YBR_RCT are rejected. They are used only by
+// compressed transfer syntaxes such as MPEG and JPEG 2000, which DecodePixels
+// can't decode.
+//
+// In the result, PhotometricInterpretation is "RGB", SamplesPerPixel is 3, and
+// each frame is []uint8 if BitsAllocated==8, or []uint16 if
+// BitsAllocated==16. The pixels are not modified.
+func ConvertToRGB(pixels *Pixels, ds *DataSet) (*Pixels, error) {
+	info, convert, err := newRGBConverter(pixels, ds)
+	if err != nil {
+		return nil, err
+	}
+	rgb := &Pixels{PixelInfo: info}
+	for _, frame := range pixels.Frames {
+		c, err := convert(frame)
+		if err != nil {
+			return nil, err
+		}
+		rgb.Frames = append(rgb.Frames, c)
+	}
+	return rgb, nil
+}
+
+// rgbConverter converts one frame to RGB.
+type rgbConverter func(frame interface{}) (interface{}, error)
+
+// newRGBConverter returns the layout of the pixels after conversion to RGB,
+// and the function that converts each frame.
+func newRGBConverter(pixels *Pixels, ds *DataSet) (PixelInfo, rgbConverter, error) {
+	info := pixels.PixelInfo
+	info.PhotometricInterpretation = "RGB"
+	info.PlanarConfiguration = 0
+	switch pi := pixels.PhotometricInterpretation; pi {
+	case "RGB", "YBR_FULL", "YBR_FULL_422", "YBR_PARTIAL_422":
+		if pixels.SamplesPerPixel != 3 || pixels.PixelRepresentation != 0 ||
+			(pixels.BitsAllocated != 8 && pixels.BitsAllocated != 16) {
+			return info, nil, fmt.Errorf("dicom.ConvertToRGB: %s with %d samples per pixel, %d bits allocated, pixel representation %d is not supported",
+				pi, pixels.SamplesPerPixel, pixels.BitsAllocated, pixels.PixelRepresentation)
+		}
+		if pi == "RGB" {
+			return info, func(frame interface{}) (interface{}, error) { return frame, nil }, nil
+		}
+		return info, newYBRConverter(pixels.BitsStored, pi == "YBR_PARTIAL_422"), nil
+	case "YBR_PARTIAL_420", "YBR_ICT", "YBR_RCT":
+		return info, nil, fmt.Errorf("dicom.ConvertToRGB: photometric interpretation '%s' is not supported; it is used only by compressed transfer syntaxes", pi)
+	case "PALETTE COLOR":
+		if pixels.SamplesPerPixel != 1 {
+			return info, nil, fmt.Errorf("dicom.ConvertToRGB: PALETTE COLOR with %d samples per pixel", pixels.SamplesPerPixel)
+		}
+		return newPaletteConverter(pixels, ds)
+	}
+	return info, nil, fmt.Errorf("dicom.ConvertToRGB: photometric interpretation '%s' is not supported", pixels.PhotometricInterpretation)
+}
+
+// newYBRConverter returns a converter from YBR_FULL, or from YBR_PARTIAL if
+// "partial" is set, to RGB. The frames must be []uint8 or []uint16.
+func newYBRConverter(bits int, partial bool) rgbConverter {
+	max := float64(uint32(1)<<uint(bits) - 1)
+	// The formulas in the standard are for 8 bits. Scale the offsets.
+	scale := math.Ldexp(1, bits-8)
+	toRGB := func(y, cb, cr float64) (r, g, b float64) {
+		cb, cr = cb-128*scale, cr-128*scale
+		if partial {
+			y = 1.1644 * (y - 16*scale)
+			return y + 1.5960*cr, y - 0.3918*cb - 0.8130*cr, y + 2.0172*cb
+		}
+		return y + 1.402*cr, y - 0.344136*cb - 0.714136*cr, y + 1.772*cb
+	}
+	clamp := func(v float64) float64 { return math.Max(0, math.Min(max, math.Round(v))) }
+	return func(frame interface{}) (interface{}, error) {
+		switch frame := frame.(type) {
+		case []uint8:
+			rgb := make([]uint8, len(frame))
+			for i := 0; i+2 < len(frame); i += 3 {
+				r, g, b := toRGB(float64(frame[i]), float64(frame[i+1]), float64(frame[i+2]))
+				rgb[i], rgb[i+1], rgb[i+2] = uint8(clamp(r)), uint8(clamp(g)), uint8(clamp(b))
+			}
+			return rgb, nil
+		case []uint16:
+			rgb := make([]uint16, len(frame))
+			for i := 0; i+2 < len(frame); i += 3 {
+				r, g, b := toRGB(float64(frame[i]), float64(frame[i+1]), float64(frame[i+2]))
+				rgb[i], rgb[i+1], rgb[i+2] = uint16(clamp(r)), uint16(clamp(g)), uint16(clamp(b))
+			}
+			return rgb, nil
+		}
+		return nil, fmt.Errorf("dicom.ConvertToRGB: unsupported YBR frame type %T", frame)
+	}
+}
+
+// Palette color lookup table elements, in order of red, green and blue.
+var (
+	paletteDescriptorTags = []dicomtag.Tag{
+		dicomtag.RedPaletteColorLookupTableDescriptor,
+		dicomtag.GreenPaletteColorLookupTableDescriptor,
+		dicomtag.BluePaletteColorLookupTableDescriptor,
+	}
+	paletteDataTags = []dicomtag.Tag{
+		dicomtag.RedPaletteColorLookupTableData,
+		dicomtag.GreenPaletteColorLookupTableData,
+		dicomtag.BluePaletteColorLookupTableData,
+	}
+	segmentedPaletteDataTags = []dicomtag.Tag{
+		dicomtag.SegmentedRedPaletteColorLookupTableData,
+		dicomtag.SegmentedGreenPaletteColorLookupTableData,
+		dicomtag.SegmentedBluePaletteColorLookupTableData,
+	}
+)
+
+// newPaletteConverter reads the palette color lookup tables of the dataset,
+// and returns a converter that expands indices into RGB.
+func newPaletteConverter(pixels *Pixels, ds *DataSet) (PixelInfo, rgbConverter, error) {
+	info := pixels.PixelInfo
+	info.PhotometricInterpretation = "RGB"
+	info.PlanarConfiguration = 0
+	info.SamplesPerPixel = 3
+	info.PixelRepresentation = 0
+	var luts [3]*lut
+	for i := range luts {
+		l, err := readPaletteLUT(ds, i, pixels.PixelRepresentation == 1)
+		if err != nil {
+			return info, nil, fmt.Errorf("dicom.ConvertToRGB: %v", err)
+		}
+		luts[i] = l
+	}
+	bits := 16
+	if luts[0].bits <= 8 && luts[1].bits <= 8 && luts[2].bits <= 8 {
+		bits = 8
+	}
+	info.BitsAllocated, info.BitsStored, info.HighBit = bits, bits, bits-1
+	return info, func(frame interface{}) (interface{}, error) {
+		indices, err := frameValues(frame)
+		if err != nil {
+			return nil, err
+		}
+		if bits == 8 {
+			rgb := make([]uint8, 3*len(indices))
+			for i, index := range indices {
+				for c, l := range luts {
+					rgb[3*i+c] = uint8(l.lookup(index))
+				}
+			}
+			return rgb, nil
+		}
+		rgb := make([]uint16, 3*len(indices))
+		for i, index := range indices {
+			for c, l := range luts {
+				rgb[3*i+c] = uint16(l.lookup(index))
+			}
+		}
+		return rgb, nil
+	}, nil
+}
+
+// readPaletteLUT reads the lookup table of the c'th color component; 0 for
+// red, 1 for green and 2 for blue.
+func readPaletteLUT(ds *DataSet, c int, signed bool) (*lut, error) {
+	desc, err := ds.FindElementByTag(paletteDescriptorTags[c])
+	if err != nil {
+		return nil, err
+	}
+	if data, err := ds.FindElementByTag(paletteDataTags[c]); err == nil {
+		return parseLUT(desc, data, signed)
+	}
+	segmented, err := ds.FindElementByTag(segmentedPaletteDataTags[c])
+	if err != nil {
+		return nil, fmt.Errorf("neither %v nor %v found",
+			dicomtag.DebugString(paletteDataTags[c]), dicomtag.DebugString(segmentedPaletteDataTags[c]))
+	}
+	l, n, err := parseLUTDescriptor(desc, signed)
+	if err != nil {
+		return nil, err
+	}
+	words, err := lutData(segmented, -1, 16)
+	if err != nil {
+		return nil, err
+	}
+	if l.data, err = expandSegmentedLUT(words, n); err != nil {
+		return nil, fmt.Errorf("%v: %v", dicomtag.DebugString(segmented.Tag), err)
+	}
+	if len(l.data) < n {
+		return nil, fmt.Errorf("%v expands to %d entries, but %v says %d",
+			dicomtag.DebugString(segmented.Tag), len(l.data), dicomtag.DebugString(desc.Tag), n)
+	}
+	l.data = l.data[:n]
+	return l, nil
+}
+
+// Opcodes of segmented palette color lookup tables. P3.3 C.7.9.2.
+const (
+	discreteSegment = 0
+	linearSegment   = 1
+	indirectSegment = 2
+)
+
+// expandSegmentedLUT expands segmented lookup table data into at most "n"
+// entries. P3.3 C.7.9.2.
+func expandSegmentedLUT(data []uint16, n int) ([]uint16, error) {
+	var out []uint16
+	var expand func(segments []uint16, numSegments int, depth int) error
+	expand = func(segments []uint16, numSegments int, depth int) error {
+		i := 0
+		for s := 0; i < len(segments) && (numSegments < 0 || s < numSegments) && len(out) < n; s++ {
+			if i+1 >= len(segments) {
+				return fmt.Errorf("segment at word %d is truncated", i)
+			}
+			opcode, length := segments[i], int(segments[i+1])
+			i += 2
+			switch opcode {
+			case discreteSegment:
+				if i+length > len(segments) {
+					return fmt.Errorf("discrete segment at word %d is truncated", i-2)
+				}
+				out = append(out, segments[i:i+length]...)
+				i += length
+			case linearSegment:
+				if i >= len(segments) {
+					return fmt.Errorf("linear segment at word %d is truncated", i-2)
+				}
+				if len(out) == 0 {
+					return fmt.Errorf("linear segment at word %d has no start value", i-2)
+				}
+				y0, y1 := float64(out[len(out)-1]), float64(segments[i])
+				i++
+				for j := 1; j <= length; j++ {
+					out = append(out, uint16(math.Round(y0+(y1-y0)*float64(j)/float64(length))))
+				}
+			case indirectSegment:
+				if depth > 0 {
+					return fmt.Errorf("nested indirect segment at word %d", i-2)
+				}
+				if i+1 >= len(segments) {
+					return fmt.Errorf("indirect segment at word %d is truncated", i-2)
+				}
+				// The offset is a 32-bit value, split into two words, low
+				// word first. Like other implementations, it's treated as
+				// an index into the segmented data.
+				offset := int(segments[i]) | int(segments[i+1])<<16
+				i += 2
+				if offset >= len(data) {
+					return fmt.Errorf("indirect segment at word %d points beyond the data", i-4)
+				}
+				if err := expand(data[offset:], length, depth+1); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unknown segment opcode %d at word %d", opcode, i-2)
+			}
+		}
+		return nil
+	}
+	if err := expand(data, -1, 0); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
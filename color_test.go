@@ -0,0 +1,139 @@
+package dicom_test
+
+import (
+	"encoding/binary"
+	"image"
+	"testing"
+
+	"github.com/msz-kp/go-dicom"
+	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustConvertToRGB(t *testing.T, ds *dicom.DataSet) *dicom.Pixels {
+	pixels, err := dicom.DecodePixels(ds)
+	require.NoError(t, err)
+	rgb, err := dicom.ConvertToRGB(pixels, ds)
+	require.NoError(t, err)
+	assert.Equal(t, "RGB", rgb.PhotometricInterpretation)
+	assert.Equal(t, 3, rgb.SamplesPerPixel)
+	return rgb
+}
+
+// newColorImage creates a 1xN 8-bit image with three samples per pixel.
+func newColorImage(pi string, columns int, data []byte, attrs ...interface{}) *dicom.DataSet {
+	attrs = append([]interface{}{
+		dicomtag.PhotometricInterpretation, pi, dicomtag.SamplesPerPixel, uint16(3),
+		dicomtag.Rows, uint16(1), dicomtag.Columns, uint16(columns), dicomtag.BitsAllocated, uint16(8),
+	}, attrs...)
+	return newImage(data, attrs...)
+}
+
+func TestConvertRGB(t *testing.T) {
+	ds := newColorImage("RGB", 2, []byte{1, 4, 2, 5, 3, 6}, dicomtag.PlanarConfiguration, uint16(1))
+	rgb := mustConvertToRGB(t, ds)
+	assert.Equal(t, []interface{}{[]uint8{1, 2, 3, 4, 5, 6}}, rgb.Frames)
+	assert.Equal(t, 0, rgb.PlanarConfiguration)
+}
+
+func TestConvertYBR(t *testing.T) {
+	ds := newColorImage("YBR_FULL", 2, []byte{128, 128, 128, 76, 85, 255})
+	rgb := mustConvertToRGB(t, ds)
+	assert.Equal(t, []interface{}{[]uint8{128, 128, 128, 254, 0, 0}}, rgb.Frames)
+
+	// Y1 Y2 Cb Cr.
+	ds = newColorImage("YBR_FULL_422", 2, []byte{128, 76, 128, 128})
+	rgb = mustConvertToRGB(t, ds)
+	assert.Equal(t, []interface{}{[]uint8{128, 128, 128, 76, 76, 76}}, rgb.Frames)
+
+	ds = newColorImage("YBR_PARTIAL_422", 2, []byte{235, 16, 128, 128})
+	rgb = mustConvertToRGB(t, ds)
+	assert.Equal(t, []interface{}{[]uint8{255, 255, 255, 0, 0, 0}}, rgb.Frames)
+
+	ds = newColorImage("YBR_FULL_422", 1, []byte{128, 128, 128, 128})
+	_, err := dicom.DecodePixels(ds)
+	assert.Error(t, err, "odd number of columns")
+
+	// YBR_PARTIAL_420 is found only in compressed pixel data.
+	ds = newColorImage("YBR_PARTIAL_420", 1, []byte{235, 128, 128})
+	pixels, err := dicom.DecodePixels(ds)
+	require.NoError(t, err)
+	_, err = dicom.ConvertToRGB(pixels, ds)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "YBR_PARTIAL_420")
+
+	img, err := dicom.Render(newColorImage("YBR_FULL", 1, []byte{76, 85, 255}), dicom.RenderOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []uint8{254, 0, 0, 255}, img.(*image.RGBA).Pix)
+}
+
+// lutBytes encodes LUT entries as an OW value.
+func lutBytes(entries ...uint16) []byte {
+	b := make([]byte, 2*len(entries))
+	for i, v := range entries {
+		binary.LittleEndian.PutUint16(b[2*i:], v)
+	}
+	return b
+}
+
+func TestConvertPalette(t *testing.T) {
+	ds := newImage([]byte{0, 1, 2, 9},
+		dicomtag.PhotometricInterpretation, "PALETTE COLOR",
+		dicomtag.Rows, uint16(1), dicomtag.Columns, uint16(4), dicomtag.BitsAllocated, uint16(8),
+		// Three entries for indices 1..3.
+		dicomtag.RedPaletteColorLookupTableDescriptor, []interface{}{uint16(3), uint16(1), uint16(8)},
+		dicomtag.GreenPaletteColorLookupTableDescriptor, []interface{}{uint16(3), uint16(1), uint16(8)},
+		dicomtag.BluePaletteColorLookupTableDescriptor, []interface{}{uint16(3), uint16(1), uint16(8)},
+		dicomtag.RedPaletteColorLookupTableData, lutBytes(10, 20, 30),
+		dicomtag.GreenPaletteColorLookupTableData, lutBytes(40, 50, 60),
+		dicomtag.BluePaletteColorLookupTableData, lutBytes(70, 80, 90))
+	rgb := mustConvertToRGB(t, ds)
+	// Indices out of range are clamped.
+	assert.Equal(t, []interface{}{[]uint8{10, 40, 70, 10, 40, 70, 20, 50, 80, 30, 60, 90}}, rgb.Frames)
+
+	// 16-bit entries.
+	for _, tag := range []dicomtag.Tag{
+		dicomtag.RedPaletteColorLookupTableDescriptor,
+		dicomtag.GreenPaletteColorLookupTableDescriptor,
+		dicomtag.BluePaletteColorLookupTableDescriptor,
+	} {
+		ds.Set(dicom.MustNewElement(tag, uint16(3), uint16(1), uint16(16)))
+	}
+	ds.Set(dicom.MustNewElement(dicomtag.RedPaletteColorLookupTableData, lutBytes(0, 0x8000, 0xffff)))
+	rgb = mustConvertToRGB(t, ds)
+	assert.Equal(t, 16, rgb.BitsAllocated)
+	assert.Equal(t, []uint16{0, 40, 70, 0, 40, 70, 0x8000, 50, 80, 0xffff, 60, 90}, rgb.Frames[0])
+
+	img, err := dicom.Render(ds, dicom.RenderOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []uint8{128, 0, 0, 255}, img.(*image.RGBA).Pix[8:12])
+
+	ds.Remove(dicomtag.GreenPaletteColorLookupTableData)
+	_, err = dicom.Render(ds, dicom.RenderOptions{})
+	assert.Error(t, err)
+}
+
+func TestConvertSegmentedPalette(t *testing.T) {
+	ds := newImage([]byte{0, 1, 2, 3, 4},
+		dicomtag.PhotometricInterpretation, "PALETTE COLOR",
+		dicomtag.Rows, uint16(1), dicomtag.Columns, uint16(5), dicomtag.BitsAllocated, uint16(8),
+		dicomtag.RedPaletteColorLookupTableDescriptor, []interface{}{uint16(5), uint16(0), uint16(16)},
+		dicomtag.GreenPaletteColorLookupTableDescriptor, []interface{}{uint16(5), uint16(0), uint16(16)},
+		dicomtag.BluePaletteColorLookupTableDescriptor, []interface{}{uint16(5), uint16(0), uint16(16)},
+		// A discrete segment of 0, and a linear one to 400.
+		dicomtag.SegmentedRedPaletteColorLookupTableData, lutBytes(0, 1, 0, 1, 4, 400),
+		// A discrete segment, an indirect one that repeats it, and another
+		// discrete one.
+		dicomtag.SegmentedGreenPaletteColorLookupTableData, lutBytes(0, 1, 7, 2, 1, 0, 0, 0, 3, 7, 0, 0),
+		// The linear segment starts from the last value.
+		dicomtag.SegmentedBluePaletteColorLookupTableData, lutBytes(0, 2, 10, 20, 1, 3, 50))
+	rgb := mustConvertToRGB(t, ds)
+	assert.Equal(t, []uint16{
+		0, 7, 10,
+		100, 7, 20,
+		200, 7, 30,
+		300, 0, 40,
+		400, 0, 50,
+	}, rgb.Frames[0])
+}
@@ -0,0 +1,153 @@
+package dicom
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/msz-kp/go-dicom/dicomtag"
+)
+
+// The methods below modify a DataSet while keeping Elements in ascending tag
+// order, as required by P3.5 7.1. They keep an index from tag to position in
+// Elements up to date, and Has and FindElementByTag look up elements through
+// it. Reads never build or change the index, so they can run concurrently. A DataSet that has never been indexed,
+// e.g., one created as a struct literal, is scanned until it is first modified
+// or Reindex is called. ReadDataSet, Clone and Marshal return indexed
+// datasets.
+//
+// Changing Elements directly, e.g., "ds.Elements[i] = elem", makes the index
+// stale; call Reindex afterwards. The index is also ignored if the length of
+// Elements has changed since it was built, but that doesn't catch every
+// direct change.
+
+// ItemDataSet returns a DataSet view of a sequence item, i.e., an element with
+// Tag==dicomtag.Item. Changes made through the view's Set, Add, Replace and
+// Remove methods are reflected in the item's Value.
+func (e *Element) ItemDataSet() (*DataSet, error) {
+	if e.Tag != dicomtag.Item {
+		return nil, fmt.Errorf("%v: not an item", dicomtag.DebugString(e.Tag))
+	}
+	ds := &DataSet{Elements: e.GetElements(), item: e}
+	ds.Reindex()
+	return ds, nil
+}
+
+// find returns the position of the first element with the given tag in
+// f.Elements. It uses the index if it is up to date, and scans f.Elements
+// otherwise. It never modifies "f".
+func (f *DataSet) find(tag dicomtag.Tag) (int, bool) {
+	if f.indexValid() {
+		i, ok := f.index[tag]
+		return i, ok
+	}
+	for i, elem := range f.Elements {
+		if elem.Tag == tag {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// lookup is like find, but it builds the index if needed. It must be called
+// only by the methods that modify the dataset.
+func (f *DataSet) lookup(tag dicomtag.Tag) (int, bool) {
+	if !f.indexValid() {
+		f.Reindex()
+	}
+	i, ok := f.index[tag]
+	return i, ok
+}
+
+func (f *DataSet) indexValid() bool {
+	return f.index != nil && len(f.Elements) == f.indexedLen
+}
+
+// Reindex rebuilds the index that Has, FindElementByTag and the methods that
+// modify the dataset use to find elements. It must be called after Elements is
+// changed directly rather than through Set, Add, Replace and Remove.
+func (f *DataSet) Reindex() {
+	f.index = make(map[dicomtag.Tag]int, len(f.Elements))
+	for i := len(f.Elements) - 1; i >= 0; i-- {
+		// If a tag appears more than once, the first one wins.
+		f.index[f.Elements[i].Tag] = i
+	}
+	f.indexedLen = len(f.Elements)
+}
+
+// modified updates the index and the underlying item, if any, after
+// f.Elements[start:] have moved.
+func (f *DataSet) modified(start int) {
+	for i := len(f.Elements) - 1; i >= start; i-- {
+		tag := f.Elements[i].Tag
+		if j, ok := f.index[tag]; ok && j < start {
+			// An earlier element of the same tag, which didn't move.
+			continue
+		}
+		f.index[tag] = i
+	}
+	f.indexedLen = len(f.Elements)
+	if f.item != nil {
+		values := make([]interface{}, len(f.Elements))
+		for i, elem := range f.Elements {
+			values[i] = elem
+		}
+		f.item.Value = values
+	}
+}
+
+// Has checks if the dataset contains an element with the given tag.
+func (f *DataSet) Has(tag dicomtag.Tag) bool {
+	_, ok := f.find(tag)
+	return ok
+}
+
+// Set stores "elem" in the dataset. It replaces the existing element with the
+// same tag, if any. Else, it inserts "elem" so that the elements stay sorted by
+// tag.
+func (f *DataSet) Set(elem *Element) {
+	if i, ok := f.lookup(elem.Tag); ok {
+		f.Elements[i] = elem
+		f.modified(i)
+		return
+	}
+	i := sort.Search(len(f.Elements), func(i int) bool {
+		return f.Elements[i].Tag.Compare(elem.Tag) > 0
+	})
+	f.Elements = append(f.Elements, nil)
+	copy(f.Elements[i+1:], f.Elements[i:])
+	f.Elements[i] = elem
+	f.modified(i)
+}
+
+// Add inserts "elem" into the dataset, keeping the elements sorted by tag. It
+// returns an error if the dataset already has an element with the same tag.
+func (f *DataSet) Add(elem *Element) error {
+	if _, ok := f.lookup(elem.Tag); ok {
+		return fmt.Errorf("%v: element already exists", dicomtag.DebugString(elem.Tag))
+	}
+	f.Set(elem)
+	return nil
+}
+
+// Replace replaces the element with the same tag as "elem". It returns an error
+// if the dataset has no such element.
+func (f *DataSet) Replace(elem *Element) error {
+	if _, ok := f.lookup(elem.Tag); !ok {
+		return fmt.Errorf("%v: element not found", dicomtag.DebugString(elem.Tag))
+	}
+	f.Set(elem)
+	return nil
+}
+
+// Remove removes the element with the given tag. It returns false if the
+// dataset has no such element.
+func (f *DataSet) Remove(tag dicomtag.Tag) bool {
+	i, ok := f.lookup(tag)
+	if !ok {
+		return false
+	}
+	delete(f.index, tag)
+	f.Elements = append(f.Elements[:i], f.Elements[i+1:]...)
+	f.modified(i)
+	return true
+}
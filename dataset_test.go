@@ -0,0 +1,150 @@
+package dicom_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/msz-kp/go-dicom"
+	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dataSetTags(elems []*dicom.Element) []dicomtag.Tag {
+	var tags []dicomtag.Tag
+	for _, elem := range elems {
+		tags = append(tags, elem.Tag)
+	}
+	return tags
+}
+
+func TestDataSetMutation(t *testing.T) {
+	ds := &dicom.DataSet{}
+	ds.Set(dicom.MustNewElement(dicomtag.PatientName, "Doe^John"))
+	ds.Set(dicom.MustNewElement(dicomtag.Modality, "CT"))
+	require.NoError(t, ds.Add(dicom.MustNewElement(dicomtag.Rows, uint16(512))))
+	require.NoError(t, ds.Add(dicom.MustNewElement(dicomtag.TransferSyntaxUID, "1.2.840.10008.1.2")))
+	require.NoError(t, ds.Add(dicom.MustNewElement(dicomtag.PatientID, "12345")))
+	assert.Equal(t, []dicomtag.Tag{
+		dicomtag.TransferSyntaxUID, dicomtag.Modality, dicomtag.PatientName, dicomtag.PatientID, dicomtag.Rows,
+	}, dataSetTags(ds.Elements))
+
+	assert.True(t, ds.Has(dicomtag.PatientID))
+	assert.False(t, ds.Has(dicomtag.Columns))
+	assert.Error(t, ds.Add(dicom.MustNewElement(dicomtag.PatientID, "67890")))
+
+	require.NoError(t, ds.Replace(dicom.MustNewElement(dicomtag.PatientID, "67890")))
+	elem, err := ds.FindElementByTag(dicomtag.PatientID)
+	require.NoError(t, err)
+	assert.Equal(t, "67890", elem.MustGetString())
+	assert.Error(t, ds.Replace(dicom.MustNewElement(dicomtag.Columns, uint16(512))))
+
+	assert.True(t, ds.Remove(dicomtag.Modality))
+	assert.False(t, ds.Remove(dicomtag.Modality))
+	assert.False(t, ds.Has(dicomtag.Modality))
+	assert.Equal(t, []dicomtag.Tag{
+		dicomtag.TransferSyntaxUID, dicomtag.PatientName, dicomtag.PatientID, dicomtag.Rows,
+	}, dataSetTags(ds.Elements))
+	for _, tag := range dataSetTags(ds.Elements) {
+		elem, err := ds.FindElementByTag(tag)
+		require.NoError(t, err)
+		assert.Equal(t, tag, elem.Tag)
+	}
+	elem, err = ds.FindElementByName("Rows")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(512), elem.MustGetUInt16())
+}
+
+func TestDataSetIndexAfterDirectChange(t *testing.T) {
+	ds := mustReadFile(t, "examples/IM-0001-0001.dcm", dicom.ReadOptions{DropPixelData: true})
+	ds.Set(dicom.MustNewElement(dicomtag.PatientComments, "foo"))
+
+	// Elements overwritten without going through the DataSet methods need
+	// Reindex.
+	for i, e := range ds.Elements {
+		if e.Tag == dicomtag.PatientName {
+			ds.Elements[i] = dicom.MustNewElement(dicomtag.PatientBirthName, "Doe^Jane")
+		}
+	}
+	ds.Reindex()
+	elem, err := ds.FindElementByTag(dicomtag.PatientBirthName)
+	require.NoError(t, err)
+	assert.Equal(t, "Doe^Jane", elem.MustGetString())
+	assert.False(t, ds.Has(dicomtag.PatientName))
+	// Set replaces the element instead of adding another one.
+	ds.Set(dicom.MustNewElement(dicomtag.PatientBirthName, "Doe^Joan"))
+	assert.Len(t, elementsWithTag(ds, dicomtag.PatientBirthName), 1)
+	assert.Error(t, ds.Add(dicom.MustNewElement(dicomtag.PatientBirthName, "Doe^Joan")))
+
+	ds.Elements = ds.Elements[1:]
+	ds.Reindex()
+	assert.False(t, ds.Has(dicomtag.FileMetaInformationGroupLength))
+	ds.Elements = append(ds.Elements, dicom.MustNewElement(dicomtag.DataSetTrailingPadding, []byte{0, 0}))
+	ds.Reindex()
+	assert.True(t, ds.Has(dicomtag.DataSetTrailingPadding))
+	assert.True(t, ds.Remove(dicomtag.DataSetTrailingPadding))
+	assert.True(t, ds.Remove(dicomtag.PatientComments))
+	assert.False(t, ds.Has(dicomtag.PatientComments))
+
+	// The first of duplicate elements is found, before and after
+	// modifications.
+	ds = &dicom.DataSet{Elements: []*dicom.Element{
+		dicom.MustNewElement(dicomtag.PatientName, "Doe^John"),
+		dicom.MustNewElement(dicomtag.PatientName, "Doe^Jane"),
+	}}
+	ds.Reindex()
+	firstName := func() string {
+		elem, err := ds.FindElementByTag(dicomtag.PatientName)
+		require.NoError(t, err)
+		return elem.MustGetString()
+	}
+	assert.Equal(t, "Doe^John", firstName())
+	ds.Set(dicom.MustNewElement(dicomtag.Modality, "CT"))
+	assert.Equal(t, "Doe^John", firstName())
+	assert.True(t, ds.Remove(dicomtag.PatientName))
+	assert.Equal(t, "Doe^Jane", firstName())
+}
+
+func TestDataSetConcurrentReads(t *testing.T) {
+	ds := mustReadFile(t, "examples/IM-0001-0001.dcm", dicom.ReadOptions{DropPixelData: true})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ds.FindElementByTag(dicomtag.PatientName)
+			assert.NoError(t, err)
+			assert.True(t, ds.Has(dicomtag.Rows))
+		}()
+	}
+	wg.Wait()
+}
+
+// elementsWithTag returns the elements of the dataset with the given tag.
+func elementsWithTag(ds *dicom.DataSet, tag dicomtag.Tag) []*dicom.Element {
+	var elems []*dicom.Element
+	for _, elem := range ds.Elements {
+		if elem.Tag == tag {
+			elems = append(elems, elem)
+		}
+	}
+	return elems
+}
+
+func TestItemDataSet(t *testing.T) {
+	item := dicom.MustNewElement(dicomtag.Item,
+		dicom.MustNewElement(dicomtag.ReferencedSOPInstanceUID, "1.2.3"))
+	seq := dicom.MustNewElement(dicomtag.ReferencedImageSequence, item)
+	ds := &dicom.DataSet{Elements: []*dicom.Element{seq}}
+
+	itemDS, err := ds.Elements[0].Value[0].(*dicom.Element).ItemDataSet()
+	require.NoError(t, err)
+	itemDS.Set(dicom.MustNewElement(dicomtag.ReferencedSOPClassUID, "1.2.840.10008.5.1.4.1.1.2"))
+	assert.Equal(t, []dicomtag.Tag{dicomtag.ReferencedSOPClassUID, dicomtag.ReferencedSOPInstanceUID},
+		dataSetTags(item.GetElements()))
+	assert.True(t, itemDS.Remove(dicomtag.ReferencedSOPInstanceUID))
+	assert.Equal(t, []dicomtag.Tag{dicomtag.ReferencedSOPClassUID}, dataSetTags(item.GetElements()))
+
+	_, err = seq.ItemDataSet()
+	assert.Error(t, err)
+}
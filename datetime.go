@@ -0,0 +1,362 @@
+package dicom
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/msz-kp/go-dicom/dicomtag"
+)
+
+// InvalidHour is stored in TimeInfo.Hour of the endTime returned by ParseTime
+// when the input isn't a range.
+const InvalidHour = -1
+
+// Precision is the least significant component present in a TM or DT string.
+// DICOM allows the trailing components to be omitted. P3.5 6.2.
+type Precision int
+
+const (
+	PrecisionYear Precision = iota
+	PrecisionMonth
+	PrecisionDay
+	PrecisionHour
+	PrecisionMinute
+	PrecisionSecond
+	// PrecisionFraction means the string has fractional seconds.
+	PrecisionFraction
+)
+
+// TimeInfo is a result of parsing a time string (VR TM).
+type TimeInfo struct {
+	// Input string.
+	Str string
+	// Results of parsing Str. Components that are omitted in Str are 0.
+	Hour       int // Hour of day, in range [0,23].
+	Minute     int // Minute of hour, in range [0,59].
+	Second     int // Second of minute, in range [0,60]. 60 is for a leap second.
+	Nanosecond int // Fractional second, in range [0,999999000].
+	// The least significant component found in Str. One of PrecisionHour,
+	// PrecisionMinute, PrecisionSecond, or PrecisionFraction.
+	Precision Precision
+}
+
+func (t TimeInfo) String() string {
+	// Convert to ISO-8601
+	s := fmt.Sprintf("%02d:%02d:%02d", t.Hour, t.Minute, t.Second)
+	if t.Precision == PrecisionFraction {
+		s += fmt.Sprintf(".%06d", t.Nanosecond/1000)
+	}
+	return s
+}
+
+// DateTimeInfo is a result of parsing a datetime string (VR DT).
+type DateTimeInfo struct {
+	// Input string.
+	Str string
+	// Results of parsing Str. Components that are omitted in Str are 1 for
+	// Month and Day, and 0 for the rest.
+	Year       int // Year (CE), in range [0,9999]. E.g., 2015
+	Month      int // Month of year, in range [1,12].
+	Day        int // Day of month, in range [1,31].
+	Hour       int // Hour of day, in range [0,23].
+	Minute     int // Minute of hour, in range [0,59].
+	Second     int // Second of minute, in range [0,60]. 60 is for a leap second.
+	Nanosecond int // Fractional second, in range [0,999999000].
+	// The least significant component found in Str, not counting the UTC
+	// offset.
+	Precision Precision
+	// The "&ZZXX" offset from UTC found in Str, as a fixed zone. It is nil
+	// if Str has no offset.
+	Location *time.Location
+}
+
+func (d DateTimeInfo) String() string {
+	// Convert to ISO-8601
+	s := fmt.Sprintf("%04d-%02d-%02dT%02d:%02d:%02d", d.Year, d.Month, d.Day, d.Hour, d.Minute, d.Second)
+	if d.Precision == PrecisionFraction {
+		s += fmt.Sprintf(".%06d", d.Nanosecond/1000)
+	}
+	if d.Location != nil {
+		_, offset := time.Date(d.Year, time.Month(d.Month), d.Day, 0, 0, 0, 0, d.Location).Zone()
+		s += formatUTCOffset(offset, ":")
+	}
+	return s
+}
+
+var (
+	// A time string is of form "HHMMSS.FFFFFF", where the trailing
+	// components can be omitted. ACR-NEMA300 uses "HH:MM:SS.FFFFFF", which
+	// is not compliant according to P3.5 6.2, but it still happens in real
+	// life.
+	timeRE = regexp.MustCompile(`^(\d\d)(?::?(\d\d)(?::?(\d\d)(?:\.(\d{1,6}))?)?)?$`)
+
+	// A datetime string is of form "YYYYMMDDHHMMSS.FFFFFF&ZZXX", where the
+	// trailing components, as well as the UTC offset "&ZZXX", can be
+	// omitted.
+	dateTimeRE = regexp.MustCompile(`^(\d{4})(?:(\d\d)(?:(\d\d)(?:(\d\d)(?:(\d\d)(?:(\d\d)(?:\.(\d{1,6}))?)?)?)?)?)?([+-]\d{4})?$`)
+
+	utcOffsetRE = regexp.MustCompile(`^([+-])(\d\d)(\d\d)$`)
+)
+
+// parseFraction converts the digits after the decimal point into nanoseconds.
+func parseFraction(s string) int {
+	v, _ := strconv.Atoi(s + strings.Repeat("0", 9-len(s)))
+	return v
+}
+
+// ParseTime parses a time string or time-range string as defined for the VR
+// type "TM".
+//
+// If "s" is for a point in time, startTime will show that point, and endTime
+// will have Hour==InvalidHour. If "s" is for a range of times, [startTime,
+// endTime] stores the range. An omitted lower bound is 00:00:00, and an omitted
+// upper bound is 23:59:59.999999.
+func ParseTime(s string) (startTime, endTime TimeInfo, err error) {
+	s = strings.Trim(s, " ")
+	if i := strings.IndexByte(s, '-'); i >= 0 { // Time range.
+		if i == 0 {
+			startTime = TimeInfo{Precision: PrecisionHour}
+		} else if startTime, err = parseSingleTime(s[:i]); err != nil {
+			return startTime, endTime, err
+		}
+		if i == len(s)-1 {
+			endTime = TimeInfo{"", 23, 59, 59, 999999000, PrecisionFraction}
+		} else if endTime, err = parseSingleTime(s[i+1:]); err != nil {
+			return startTime, endTime, err
+		}
+		return startTime, endTime, nil
+	}
+	startTime, err = parseSingleTime(s)
+	endTime = TimeInfo{Hour: InvalidHour}
+	return startTime, endTime, err
+}
+
+func parseSingleTime(s string) (TimeInfo, error) {
+	m := timeRE.FindStringSubmatch(s)
+	if m == nil {
+		return TimeInfo{}, fmt.Errorf("%s: Failed to parse as time", s)
+	}
+	t := TimeInfo{Str: s, Precision: PrecisionHour}
+	t.Hour, _ = strconv.Atoi(m[1])
+	if m[2] != "" {
+		t.Minute, _ = strconv.Atoi(m[2])
+		t.Precision = PrecisionMinute
+	}
+	if m[3] != "" {
+		t.Second, _ = strconv.Atoi(m[3])
+		t.Precision = PrecisionSecond
+	}
+	if m[4] != "" {
+		t.Nanosecond = parseFraction(m[4])
+		t.Precision = PrecisionFraction
+	}
+	if t.Hour > 23 || t.Minute > 59 || t.Second > 60 {
+		return TimeInfo{}, fmt.Errorf("%s: Time out of range", s)
+	}
+	return t, nil
+}
+
+// ParseDateTime parses a datetime string or datetime-range string as defined
+// for the VR type "DT".
+//
+// If "s" is for a point in time, startTime will show that point, and endTime
+// will have Year==InvalidYear. If "s" is for a range of datetimes, [startTime,
+// endTime] stores the range. An omitted lower bound is 0000-01-01 00:00:00,
+// and an omitted upper bound is 9999-12-31 23:59:59.999999.
+//
+// Since a UTC offset may start with '-', a '-' is taken as a range separator
+// only if "s" doesn't parse as a single datetime.
+func ParseDateTime(s string) (startTime, endTime DateTimeInfo, err error) {
+	s = strings.Trim(s, " ")
+	if startTime, err = parseSingleDateTime(s); err == nil {
+		return startTime, DateTimeInfo{Year: InvalidYear, Month: InvalidMonth, Day: InvalidDay}, nil
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '-' {
+			continue
+		}
+		var err0, err1 error
+		startTime = DateTimeInfo{Month: 1, Day: 1, Precision: PrecisionYear}
+		if i > 0 {
+			startTime, err0 = parseSingleDateTime(s[:i])
+		}
+		endTime = DateTimeInfo{"", 9999, 12, 31, 23, 59, 59, 999999000, PrecisionFraction, nil}
+		if i < len(s)-1 {
+			endTime, err1 = parseSingleDateTime(s[i+1:])
+		}
+		if err0 == nil && err1 == nil {
+			return startTime, endTime, nil
+		}
+	}
+	return DateTimeInfo{}, DateTimeInfo{}, fmt.Errorf("%s: Failed to parse as datetime", s)
+}
+
+func parseSingleDateTime(s string) (DateTimeInfo, error) {
+	m := dateTimeRE.FindStringSubmatch(s)
+	if m == nil {
+		return DateTimeInfo{}, fmt.Errorf("%s: Failed to parse as datetime", s)
+	}
+	d := DateTimeInfo{Str: s, Month: 1, Day: 1, Precision: PrecisionYear}
+	d.Year, _ = strconv.Atoi(m[1])
+	for i, p := range []*int{&d.Month, &d.Day, &d.Hour, &d.Minute, &d.Second} {
+		if m[i+2] == "" {
+			break
+		}
+		*p, _ = strconv.Atoi(m[i+2])
+		d.Precision = PrecisionMonth + Precision(i)
+	}
+	if m[7] != "" {
+		d.Nanosecond = parseFraction(m[7])
+		d.Precision = PrecisionFraction
+	}
+	if d.Month < 1 || d.Month > 12 || d.Day < 1 || d.Day > 31 ||
+		d.Hour > 23 || d.Minute > 59 || d.Second > 60 {
+		return DateTimeInfo{}, fmt.Errorf("%s: Datetime out of range", s)
+	}
+	if m[8] != "" {
+		loc, err := ParseUTCOffset(m[8])
+		if err != nil {
+			return DateTimeInfo{}, err
+		}
+		d.Location = loc
+	}
+	return d, nil
+}
+
+// ParseUTCOffset parses an offset from UTC of form "&ZZXX", e.g., "+0900" or
+// "-0500", as found in DT values and the TimezoneOffsetFromUTC element. It
+// returns a fixed zone with that offset.
+func ParseUTCOffset(s string) (*time.Location, error) {
+	s = strings.Trim(s, " ")
+	m := utcOffsetRE.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("%s: Failed to parse as UTC offset", s)
+	}
+	hour, _ := strconv.Atoi(m[2])
+	minute, _ := strconv.Atoi(m[3])
+	// P3.5 6.2 limits the offset to [-1200,+1400].
+	if minute > 59 || hour > 14 || (m[1] == "-" && hour > 12) {
+		return nil, fmt.Errorf("%s: UTC offset out of range", s)
+	}
+	offset := hour*3600 + minute*60
+	if m[1] == "-" {
+		offset = -offset
+	}
+	return time.FixedZone(s, offset), nil
+}
+
+// TimezoneOffsetFromUTC returns the zone defined by the TimezoneOffsetFromUTC
+// element. The DA and TM values, as well as DT values without a UTC offset, in
+// the dataset are in this zone. It returns an error if the element is not
+// found.
+func (f *DataSet) TimezoneOffsetFromUTC() (*time.Location, error) {
+	elem, err := f.FindElementByTag(dicomtag.TimezoneOffsetFromUTC)
+	if err != nil {
+		return nil, err
+	}
+	s, err := elem.GetString()
+	if err != nil {
+		return nil, err
+	}
+	return ParseUTCOffset(s)
+}
+
+// Time returns the start of the day in "loc". It returns an error if the
+// date is invalid, e.g., the endDate returned by ParseDate for a non-range
+// input.
+func (d DateInfo) Time(loc *time.Location) (time.Time, error) {
+	t := time.Date(d.Year, time.Month(d.Month), d.Day, 0, 0, 0, 0, loc)
+	if d.Year < 0 || t.Year() != d.Year || int(t.Month()) != d.Month || t.Day() != d.Day {
+		return time.Time{}, fmt.Errorf("%s: Invalid date", d)
+	}
+	return t, nil
+}
+
+// Time returns the point in time on the date "d" in "loc". It's meant for
+// combining a pair of DA and TM elements, such as AcquisitionDate and
+// AcquisitionTime.
+func (t TimeInfo) Time(d DateInfo, loc *time.Location) (time.Time, error) {
+	if t.Hour < 0 {
+		return time.Time{}, fmt.Errorf("%s: Invalid time", t)
+	}
+	day, err := d.Time(loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour, t.Minute, t.Second, t.Nanosecond, loc), nil
+}
+
+// Time converts "d" to time.Time. The UTC offset found in the DT string takes
+// precedence over "loc"; "loc" is used only when the string has no offset.
+func (d DateTimeInfo) Time(loc *time.Location) (time.Time, error) {
+	if d.Location != nil {
+		loc = d.Location
+	}
+	t := time.Date(d.Year, time.Month(d.Month), d.Day, d.Hour, d.Minute, d.Second, d.Nanosecond, loc)
+	if d.Year < 0 || t.Year() != d.Year || int(t.Month()) != d.Month || t.Day() != d.Day {
+		return time.Time{}, fmt.Errorf("%s: Invalid datetime", d)
+	}
+	return t, nil
+}
+
+// FormatDate formats "t" as a date string (VR DA), e.g., "20170927".
+func FormatDate(t time.Time) string {
+	return t.Format("20060102")
+}
+
+// FormatTime formats "t" as a time string (VR TM), e.g., "103015.5". The
+// fractional second is truncated to microseconds, and is omitted if zero.
+func FormatTime(t time.Time) string {
+	return t.Truncate(time.Microsecond).Format("150405.999999")
+}
+
+// FormatDateTime formats "t" as a datetime string (VR DT), e.g.,
+// "20170927103015.5+0900". The fractional second is truncated to
+// microseconds, and is omitted if zero. The UTC offset is always included.
+func FormatDateTime(t time.Time) string {
+	t = t.Truncate(time.Microsecond)
+	_, offset := t.Zone()
+	return t.Format("20060102150405.999999") + formatUTCOffset(offset, "")
+}
+
+// formatUTCOffset formats an offset in seconds as "&ZZ<sep>XX".
+func formatUTCOffset(offset int, sep string) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("%c%02d%s%02d", sign, offset/3600, sep, offset/60%60)
+}
+
+// NewDateElement creates a new element of VR DA, such as StudyDate, with the
+// given values formatted by FormatDate.
+func NewDateElement(tag dicomtag.Tag, values ...time.Time) (*Element, error) {
+	return newTimeElement(tag, "DA", FormatDate, values)
+}
+
+// NewTimeElement creates a new element of VR TM, such as StudyTime, with the
+// given values formatted by FormatTime.
+func NewTimeElement(tag dicomtag.Tag, values ...time.Time) (*Element, error) {
+	return newTimeElement(tag, "TM", FormatTime, values)
+}
+
+// NewDateTimeElement creates a new element of VR DT, such as
+// AcquisitionDateTime, with the given values formatted by FormatDateTime.
+func NewDateTimeElement(tag dicomtag.Tag, values ...time.Time) (*Element, error) {
+	return newTimeElement(tag, "DT", FormatDateTime, values)
+}
+
+func newTimeElement(tag dicomtag.Tag, vr string, format func(time.Time) string, values []time.Time) (*Element, error) {
+	if err := checkDictionaryVR(tag, vr); err != nil {
+		return nil, err
+	}
+	strs := make([]interface{}, len(values))
+	for i, v := range values {
+		strs[i] = format(v)
+	}
+	return NewElement(tag, strs...)
+}
@@ -0,0 +1,200 @@
+package dicom_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/msz-kp/go-dicom"
+	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTime(t *testing.T) {
+	goodTimes := []struct {
+		str       string
+		iso8601   string
+		precision dicom.Precision
+	}{
+		{"07", "07:00:00", dicom.PrecisionHour},
+		{"0709", "07:09:00", dicom.PrecisionMinute},
+		{"070907", "07:09:07", dicom.PrecisionSecond},
+		{"070907.0705", "07:09:07.070500", dicom.PrecisionFraction},
+		{"070907.123456 ", "07:09:07.123456", dicom.PrecisionFraction},
+		{"07:09:07.5", "07:09:07.500000", dicom.PrecisionFraction},
+	}
+	for _, goodTime := range goodTimes {
+		s, e, err := dicom.ParseTime(goodTime.str)
+		assert.NoError(t, err, "Time:", goodTime)
+		assert.Equal(t, goodTime.iso8601, s.String())
+		assert.Equal(t, goodTime.precision, s.Precision)
+		assert.Equal(t, dicom.InvalidHour, e.Hour)
+	}
+
+	goodTimeRanges := []struct {
+		str          string
+		startISO8601 string
+		endISO8601   string
+	}{
+		{"0700-1930", "07:00:00", "19:30:00"},
+		{"-1930", "00:00:00", "19:30:00"},
+		{"0700-", "07:00:00", "23:59:59.999999"},
+	}
+	for _, r := range goodTimeRanges {
+		s, e, err := dicom.ParseTime(r.str)
+		assert.NoError(t, err)
+		assert.Equal(t, r.startISO8601, s.String())
+		assert.Equal(t, r.endISO8601, e.String())
+	}
+
+	badTimes := []string{"", "7", "070", "2400", "0760", "070907.1234567", "070907.", "07X09"}
+	for _, badTime := range badTimes {
+		_, _, err := dicom.ParseTime(badTime)
+		assert.Error(t, err, "Time:", badTime)
+	}
+}
+
+func TestParseDateTime(t *testing.T) {
+	goodDateTimes := []struct {
+		str       string
+		iso8601   string
+		precision dicom.Precision
+	}{
+		{"2017", "2017-01-01T00:00:00", dicom.PrecisionYear},
+		{"201709", "2017-09-01T00:00:00", dicom.PrecisionMonth},
+		{"20170927", "2017-09-27T00:00:00", dicom.PrecisionDay},
+		{"2017092710", "2017-09-27T10:00:00", dicom.PrecisionHour},
+		{"201709271030", "2017-09-27T10:30:00", dicom.PrecisionMinute},
+		{"20170927103015", "2017-09-27T10:30:15", dicom.PrecisionSecond},
+		{"20170927103015.25", "2017-09-27T10:30:15.250000", dicom.PrecisionFraction},
+		{"20170927103015.25+0900", "2017-09-27T10:30:15.250000+09:00", dicom.PrecisionFraction},
+		{"20170927-0530", "2017-09-27T00:00:00-05:30", dicom.PrecisionDay},
+	}
+	for _, goodDateTime := range goodDateTimes {
+		s, e, err := dicom.ParseDateTime(goodDateTime.str)
+		assert.NoError(t, err, "DateTime:", goodDateTime)
+		assert.Equal(t, goodDateTime.iso8601, s.String())
+		assert.Equal(t, goodDateTime.precision, s.Precision)
+		assert.Equal(t, dicom.InvalidYear, e.Year)
+	}
+
+	goodDateTimeRanges := []struct {
+		str          string
+		startISO8601 string
+		endISO8601   string
+	}{
+		{"20170927-20170929", "2017-09-27T00:00:00", "2017-09-29T00:00:00"},
+		{"20170927103015+0900-20170927113015+0900", "2017-09-27T10:30:15+09:00", "2017-09-27T11:30:15+09:00"},
+		{"20170927103015-0500-", "2017-09-27T10:30:15-05:00", "9999-12-31T23:59:59.999999"},
+		{"-20170929", "0000-01-01T00:00:00", "2017-09-29T00:00:00"},
+	}
+	for _, r := range goodDateTimeRanges {
+		s, e, err := dicom.ParseDateTime(r.str)
+		assert.NoError(t, err, "DateTime:", r.str)
+		assert.Equal(t, r.startISO8601, s.String())
+		assert.Equal(t, r.endISO8601, e.String())
+	}
+
+	badDateTimes := []string{"", "201", "20171327", "20170927103015.", "20170927+9900", "2017X09"}
+	for _, badDateTime := range badDateTimes {
+		_, _, err := dicom.ParseDateTime(badDateTime)
+		assert.Error(t, err, "DateTime:", badDateTime)
+	}
+}
+
+func TestParseUTCOffset(t *testing.T) {
+	loc, err := dicom.ParseUTCOffset("+0930")
+	require.NoError(t, err)
+	_, offset := time.Date(2017, 1, 1, 0, 0, 0, 0, loc).Zone()
+	assert.Equal(t, 9*3600+30*60, offset)
+
+	loc, err = dicom.ParseUTCOffset("-1200")
+	require.NoError(t, err)
+	_, offset = time.Date(2017, 1, 1, 0, 0, 0, 0, loc).Zone()
+	assert.Equal(t, -12*3600, offset)
+
+	for _, s := range []string{"0900", "+900", "+1500", "-1300", "+0960"} {
+		_, err := dicom.ParseUTCOffset(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestDateTimeToTime(t *testing.T) {
+	loc := time.FixedZone("", -5*3600)
+	date, _, err := dicom.ParseDate("20170927")
+	require.NoError(t, err)
+	v, err := date.Time(loc)
+	require.NoError(t, err)
+	assert.True(t, v.Equal(time.Date(2017, 9, 27, 0, 0, 0, 0, loc)))
+
+	start, _, err := dicom.ParseTime("103015.5")
+	require.NoError(t, err)
+	end, _, err := dicom.ParseTime("104500")
+	require.NoError(t, err)
+	startTime, err := start.Time(date, loc)
+	require.NoError(t, err)
+	endTime, err := end.Time(date, loc)
+	require.NoError(t, err)
+	assert.Equal(t, 14*time.Minute+44*time.Second+500*time.Millisecond, endTime.Sub(startTime))
+
+	// The offset in the string takes precedence.
+	dt, _, err := dicom.ParseDateTime("20170927103015.5+0900")
+	require.NoError(t, err)
+	v, err = dt.Time(loc)
+	require.NoError(t, err)
+	assert.True(t, v.Equal(time.Date(2017, 9, 27, 1, 30, 15, 500000000, time.UTC)), v)
+	dt, _, err = dicom.ParseDateTime("20170927103015.5")
+	require.NoError(t, err)
+	v, err = dt.Time(loc)
+	require.NoError(t, err)
+	assert.True(t, v.Equal(time.Date(2017, 9, 27, 15, 30, 15, 500000000, time.UTC)), v)
+
+	_, e, err := dicom.ParseDate("20170927")
+	require.NoError(t, err)
+	_, err = e.Time(loc)
+	assert.Error(t, err)
+	date, _, err = dicom.ParseDate("20170231")
+	require.NoError(t, err)
+	_, err = date.Time(loc)
+	assert.Error(t, err)
+}
+
+func TestTimezoneOffsetFromUTC(t *testing.T) {
+	ds := &dicom.DataSet{Elements: []*dicom.Element{
+		dicom.MustNewElement(dicomtag.TimezoneOffsetFromUTC, "-0500"),
+	}}
+	loc, err := ds.TimezoneOffsetFromUTC()
+	require.NoError(t, err)
+	_, offset := time.Date(2017, 1, 1, 0, 0, 0, 0, loc).Zone()
+	assert.Equal(t, -5*3600, offset)
+
+	_, err = (&dicom.DataSet{}).TimezoneOffsetFromUTC()
+	assert.Error(t, err)
+}
+
+func TestNewDateTimeElement(t *testing.T) {
+	v := time.Date(2017, 9, 27, 10, 30, 15, 250000999, time.FixedZone("", 9*3600))
+	assert.Equal(t, "20170927", dicom.FormatDate(v))
+	assert.Equal(t, "103015.25", dicom.FormatTime(v))
+	assert.Equal(t, "103015", dicom.FormatTime(v.Truncate(time.Second)))
+	assert.Equal(t, "20170927103015.25+0900", dicom.FormatDateTime(v))
+	assert.Equal(t, "20170927103015-0530", dicom.FormatDateTime(
+		time.Date(2017, 9, 27, 10, 30, 15, 0, time.FixedZone("", -(5*3600+30*60)))))
+
+	elem, err := dicom.NewDateElement(dicomtag.StudyDate, v)
+	require.NoError(t, err)
+	assert.Equal(t, "20170927", elem.MustGetString())
+	elem, err = dicom.NewTimeElement(dicomtag.StudyTime, v)
+	require.NoError(t, err)
+	assert.Equal(t, "103015.25", elem.MustGetString())
+	elem, err = dicom.NewDateTimeElement(dicomtag.AcquisitionDateTime, v)
+	require.NoError(t, err)
+	dt, _, err := dicom.ParseDateTime(elem.MustGetString())
+	require.NoError(t, err)
+	v2, err := dt.Time(time.UTC)
+	require.NoError(t, err)
+	assert.True(t, v.Truncate(time.Microsecond).Equal(v2), v2)
+
+	_, err = dicom.NewTimeElement(dicomtag.StudyDate, v)
+	assert.Error(t, err)
+}
@@ -30,6 +30,12 @@ type DataSet struct {
 	// Note: unlike pydicom, Elements also contains meta elements (those
 	// with Tag.Group==2).
 	Elements []*Element
+
+	// Index from tag to position in Elements. See dataset.go.
+	index      map[dicomtag.Tag]int
+	indexedLen int
+	// The item this dataset is a view of. See Element.ItemDataSet.
+	item *Element
 }
 
 func doassert(cond bool, values ...interface{}) {
@@ -55,7 +61,8 @@ type ReadOptions struct {
 	// value than it is read), the program will stop parsing the dicom file.
 	StopAtTag *dicomtag.Tag
 
-	// :/
+	// CP1250Fix decodes strings declared as ISO_IR 100 in windows-1250.
+	// Pass the CP1250Fix WriteOption to write such a dataset back.
 	CP1250Fix bool
 
 	// DefaultCyrillicEncoding - кодировка по умолчанию для кириллицы
@@ -169,106 +176,34 @@ func processMultiValueDSElement(elem *Element) {
 	}
 }
 
-// ReadDataSet reads a DICOM file from "io".
+// ReadDataSet reads a DICOM file from "io". The input may also be a bare
+// dataset or an ACR-NEMA file without the DICOM file header; see NewParser.
 //
 // On parse error, this function may return a non-nil dataset and a non-nil
 // error. In such case, the dataset will contain parts of the file that are
-// parsable, and error will show the first error found by the parser.
+// parsable, and error will show the first error found by the parser. If the
+// input ends in the middle of an element, the error is io.EOF, as it has
+// always been, unlike the io.ErrUnexpectedEOF that Parser.Next returns.
 func ReadDataSet(in io.Reader, options ReadOptions) (*DataSet, error) {
-	buffer := dicomio.NewDecoder(in, binary.LittleEndian, dicomio.ExplicitVR)
-	metaElems := ParseFileHeader(buffer)
-	if buffer.Error() != nil {
-		return nil, buffer.Error()
-	}
-	file := &DataSet{Elements: metaElems}
-
-	// Change the transfer syntax for the rest of the file.
-	endian, implicit, err := getTransferSyntax(file)
+	p, err := NewParser(in, options)
 	if err != nil {
 		return nil, err
 	}
-	buffer.PushTransferSyntax(endian, implicit)
-	defer buffer.PopTransferSyntax()
-
-	// Флаг для отслеживания, была ли установлена кодировка
-	charsetSet := false
-
-	// Read the list of elements.
-	for !buffer.EOF() {
-		startLen := buffer.BytesRead()
-		elem := ReadElement(buffer, options)
-		if buffer.BytesRead() <= startLen { // Avoid silent infinite looping.
-			panic(fmt.Sprintf("ReadElement failed to consume data: position %d: %v", startLen, buffer.Error()))
-		}
-		if elem == endOfDataElement {
-			// element is a pixel data and was dropped by options
-			break
-		}
-		if elem == nil {
-			// Parse error.
-			continue
-		}
-		if elem.Tag == dicomtag.SpecificCharacterSet {
-			// Set the []byte -> string decoder for the rest of the
-			// file.  It's sad that SpecificCharacterSet isn't part
-			// of metadata, but is part of regular attrs, so we need
-			// to watch out for multiple occurrences of this type of
-			// elements.
-			encodingNames, err := elem.GetCleanStrings()
-			if err != nil {
-				buffer.SetError(err)
-			} else {
-				// TODO(saito) SpecificCharacterSet may appear in a
-				// middle of a SQ or NA.  In such case, the charset seem
-				// to be scoped inside the SQ or NA. So we need to make
-				// the charset a stack.
-				cs, err := dicomio.ParseSpecificCharacterSet(encodingNames, options.CP1250Fix)
-				if err != nil {
-					buffer.SetError(err)
-				} else {
-					buffer.SetCodingSystem(cs)
-					charsetSet = true
-				}
+	file := &DataSet{}
+	for {
+		elem, err := p.Next()
+		if err != nil {
+			file.Reindex()
+			switch {
+			case err == io.EOF:
+				return file, nil
+			case p.truncated:
+				return file, io.EOF
 			}
+			return file, err
 		}
-
-		// Если это строковый элемент и кодировка не была установлена,
-		// пытаемся автоматически определить кириллическую кодировку
-		if !charsetSet && elem.Value != nil && len(elem.Value) > 0 {
-			if strVal, ok := elem.Value[0].(string); ok && strVal != "" {
-				// Проверяем, есть ли кракозябры (неправильно декодированные символы)
-				if containsGarbage(strVal) {
-					// Пытаемся декодировать с разными кириллическими кодировками
-					decoded := detectCyrillicEncoding(strVal, options.DefaultCyrillicEncoding)
-					if decoded != strVal {
-						elem.Value = []interface{}{decoded}
-					}
-				}
-			}
-		}
-
-		// Обрабатываем элементы типа DS с множественными значениями
-		if elem.VR == "DS" {
-			processMultiValueDSElement(elem)
-		}
-
-		if options.ReturnTags == nil || (options.ReturnTags != nil && tagInList(elem.Tag, options.ReturnTags)) {
-			// Очистка строковых значений от непечатаемых символов, сохраняя множественные значения
-			if elem.Value != nil {
-				cleanValues := make([]interface{}, len(elem.Value))
-				for i, value := range elem.Value {
-					if strVal, ok := value.(string); ok {
-						cleanValues[i] = FilterNonPrintable(strVal)
-					} else {
-						cleanValues[i] = value
-					}
-				}
-				elem.Value = cleanValues
-			}
-			file.Elements = append(file.Elements, elem)
-		}
+		file.Elements = append(file.Elements, elem)
 	}
-	return file, buffer.Error()
 }
 
 // containsGarbage проверяет, содержит ли строка "кракозябры"
@@ -323,12 +258,16 @@ func (e *Element) GetCleanStrings() ([]string, error) {
 	return cleanStrs, nil
 }
 
-func getTransferSyntax(ds *DataSet) (bo binary.ByteOrder, implicit dicomio.IsImplicitVR, err error) {
+func getTransferSyntaxUID(ds *DataSet) (string, error) {
 	elem, err := ds.FindElementByTag(dicomtag.TransferSyntaxUID)
 	if err != nil {
-		return nil, dicomio.UnknownVR, err
+		return "", err
 	}
-	transferSyntaxUID, err := elem.GetCleanString()
+	return elem.GetCleanString()
+}
+
+func getTransferSyntax(ds *DataSet) (bo binary.ByteOrder, implicit dicomio.IsImplicitVR, err error) {
+	transferSyntaxUID, err := getTransferSyntaxUID(ds)
 	if err != nil {
 		return nil, dicomio.UnknownVR, err
 	}
@@ -338,11 +277,22 @@ func getTransferSyntax(ds *DataSet) (bo binary.ByteOrder, implicit dicomio.IsImp
 // FindElementByName finds an element from the dataset given the element name,
 // such as "PatientName".
 func (f *DataSet) FindElementByName(name string) (*Element, error) {
-	return FindElementByName(f.Elements, name)
+	t, err := dicomtag.FindByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if i, ok := f.find(t.Tag); ok {
+		return f.Elements[i], nil
+	}
+	return nil, fmt.Errorf("Could not find element named '%s' in dicom file", name)
 }
 
 // FindElementByTag finds an element from the dataset given its tag, such as
-// Tag{0x0010, 0x0010}.
+// Tag{0x0010, 0x0010}. It looks the tag up in the index kept by Set, Add,
+// Replace and Remove; call Reindex after changing Elements directly.
 func (f *DataSet) FindElementByTag(tag dicomtag.Tag) (*Element, error) {
-	return FindElementByTag(f.Elements, tag)
+	if i, ok := f.find(tag); ok {
+		return f.Elements[i], nil
+	}
+	return nil, fmt.Errorf("%s: element not found", dicomtag.DebugString(tag))
 }
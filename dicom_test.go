@@ -2,15 +2,20 @@ package dicom_test
 
 import (
 	"bytes"
+	"compress/flate"
+	"encoding/binary"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	dicom "github.com/msz-kp/go-dicom"
+	"github.com/msz-kp/go-dicom/dicomio"
 	"github.com/msz-kp/go-dicom/dicomtag"
 	"github.com/msz-kp/go-dicom/dicomuid"
 )
@@ -106,6 +111,7 @@ func TestWriteFile(t *testing.T) {
 	testWriteFile(t, path, dicomuid.ImplicitVRLittleEndian)
 	testWriteFile(t, path, dicomuid.ExplicitVRBigEndian)
 	testWriteFile(t, path, dicomuid.ExplicitVRLittleEndian)
+	testWriteFile(t, path, dicomuid.DeflatedExplicitVRLittleEndian)
 
 	// TODO: This repository is over its data quota. Account responsible for LFS bandwidth should purchase more data packs to restore access.
 	//path = "examples/OF_DICOM.dcm"
@@ -248,3 +254,375 @@ func BenchmarkParseSingle(b *testing.B) {
 		_ = mustReadFile(b, "examples/IM-0001-0001.dcm", dicom.ReadOptions{})
 	}
 }
+
+func TestReadDeflatedDataSet(t *testing.T) {
+	// Build a file by hand: an uncompressed meta group followed by a raw
+	// deflate stream holding the dataset.
+	var buf bytes.Buffer
+	e := dicomio.NewEncoder(&buf, binary.LittleEndian, dicomio.ExplicitVR)
+	dicom.WriteFileHeader(e, []*dicom.Element{
+		dicom.MustNewElement(dicomtag.TransferSyntaxUID, dicomuid.DeflatedExplicitVRLittleEndian),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPClassUID, "1.2.840.10008.5.1.4.1.1.88.11"),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPInstanceUID, "1.2.3.4.5.6.7"),
+	}, &dicom.WriteOptSet{})
+	require.NoError(t, e.Error())
+	body := dicomio.NewBytesEncoder(binary.LittleEndian, dicomio.ExplicitVR)
+	dicom.WriteElement(body, dicom.MustNewElement(dicomtag.PatientName, "Alice^Doe"), &dicom.WriteOptSet{})
+	dicom.WriteElement(body, dicom.MustNewElement(dicomtag.PatientID, "12345"), &dicom.WriteOptSet{})
+	zw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	require.NoError(t, err)
+	_, err = zw.Write(body.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	ds, err := dicom.ReadDataSet(&buf, dicom.ReadOptions{})
+	require.NoError(t, err)
+	elem, err := ds.FindElementByTag(dicomtag.PatientName)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice^Doe", elem.MustGetString())
+	elem, err = ds.FindElementByTag(dicomtag.PatientID)
+	require.NoError(t, err)
+	assert.Equal(t, "12345", elem.MustGetString())
+}
+
+func TestWriteDeflatedDataSet(t *testing.T) {
+	ds := dicom.DataSet{Elements: []*dicom.Element{
+		dicom.MustNewElement(dicomtag.TransferSyntaxUID, dicomuid.DeflatedExplicitVRLittleEndian),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPClassUID, "1.2.840.10008.5.1.4.1.1.88.11"),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPInstanceUID, "1.2.3.4.5.6.7"),
+		dicom.MustNewElement(dicomtag.StudyDescription, strings.Repeat("compressible ", 100)),
+	}}
+	var plain, best bytes.Buffer
+	require.NoError(t, dicom.WriteDataSet(&plain, &ds, dicom.DeflateLevel(flate.NoCompression)))
+	require.NoError(t, dicom.WriteDataSet(&best, &ds, dicom.DeflateLevel(flate.BestCompression)))
+	assert.True(t, best.Len() < plain.Len(), "%d %d", best.Len(), plain.Len())
+	// The meta group is not compressed.
+	assert.Contains(t, best.String(), dicomuid.DeflatedExplicitVRLittleEndian)
+
+	ds2, err := dicom.ReadDataSet(&best, dicom.ReadOptions{})
+	require.NoError(t, err)
+	elem, err := ds2.FindElementByTag(dicomtag.StudyDescription)
+	require.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(strings.Repeat("compressible ", 100)), elem.MustGetString())
+}
+
+func TestWriteDeflatedDataSetError(t *testing.T) {
+	ds := dicom.DataSet{Elements: []*dicom.Element{
+		dicom.MustNewElement(dicomtag.TransferSyntaxUID, dicomuid.DeflatedExplicitVRLittleEndian),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPClassUID, "1.2.840.10008.5.1.4.1.1.88.11"),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPInstanceUID, "1.2.3.4.5.6.7"),
+		dicom.MustNewElement(dicomtag.StudyDescription, "test"),
+	}}
+	// An invalid level is found before anything is written.
+	var buf bytes.Buffer
+	assert.Error(t, dicom.WriteDataSet(&buf, &ds, dicom.DeflateLevel(42)))
+	assert.Zero(t, buf.Len())
+
+	// A string that can't be encoded fails after the header is written,
+	// but the compressed stream is still terminated.
+	ds.Set(dicom.MustNewElement(dicomtag.SpecificCharacterSet, "ISO_IR 100"))
+	ds.Set(dicom.MustNewElement(dicomtag.PatientName, "Иванов"))
+	buf.Reset()
+	assert.Error(t, dicom.WriteDataSet(&buf, &ds))
+	meta, err := dicom.ReadDataSetInBytes(buf.Bytes(), dicom.ReadOptions{StopAtTag: &dicomtag.SpecificCharacterSet})
+	require.NoError(t, err)
+	groupLength, err := meta.FindElementByTag(dicomtag.FileMetaInformationGroupLength)
+	require.NoError(t, err)
+	// The preamble, "DICM", and the group length element precede the group.
+	n := 128 + 4 + 12 + int(groupLength.MustGetUInt32())
+	_, err = ioutil.ReadAll(flate.NewReader(bytes.NewReader(buf.Bytes()[n:])))
+	assert.NoError(t, err)
+}
+
+func TestReadHeaderlessDataSet(t *testing.T) {
+	tests := []struct {
+		bo        binary.ByteOrder
+		implicit  dicomio.IsImplicitVR
+		syntaxUID string
+	}{
+		{binary.LittleEndian, dicomio.ImplicitVR, dicomuid.ImplicitVRLittleEndian},
+		{binary.LittleEndian, dicomio.ExplicitVR, dicomuid.ExplicitVRLittleEndian},
+		{binary.BigEndian, dicomio.ExplicitVR, dicomuid.ExplicitVRBigEndian},
+	}
+	for _, test := range tests {
+		e := dicomio.NewBytesEncoder(test.bo, test.implicit)
+		// ACR-NEMA files usually start with a group length element.
+		dicom.WriteElement(e, dicom.MustNewElement(dicomtag.Tag{Group: 0x0008, Element: 0x0000}, uint32(0)), &dicom.WriteOptSet{})
+		dicom.WriteElement(e, dicom.MustNewElement(dicomtag.Modality, "CT"), &dicom.WriteOptSet{})
+		dicom.WriteElement(e, dicom.MustNewElement(dicomtag.PatientName, "Alice^Doe"), &dicom.WriteOptSet{})
+		dicom.WriteElement(e, dicom.MustNewElement(dicomtag.Rows, uint16(512)), &dicom.WriteOptSet{})
+		ds, err := dicom.ReadDataSetInBytes(e.Bytes(), dicom.ReadOptions{})
+		require.NoError(t, err, test.syntaxUID)
+		elem, err := ds.FindElementByTag(dicomtag.TransferSyntaxUID)
+		require.NoError(t, err)
+		assert.Equal(t, test.syntaxUID, elem.MustGetString())
+		elem, err = ds.FindElementByTag(dicomtag.PatientName)
+		require.NoError(t, err)
+		assert.Equal(t, "Alice^Doe", elem.MustGetString())
+		elem, err = ds.FindElementByTag(dicomtag.Rows)
+		require.NoError(t, err)
+		assert.Equal(t, uint16(512), elem.MustGetUInt16())
+	}
+
+	// A Part 10 file without the preamble.
+	data, err := ioutil.ReadFile("examples/IM-0001-0003.dcm")
+	require.NoError(t, err)
+	ds, err := dicom.ReadDataSetInBytes(data[128:], dicom.ReadOptions{})
+	require.NoError(t, err)
+	elem, err := ds.FindElementByTag(dicomtag.PatientID)
+	require.NoError(t, err)
+	assert.Equal(t, "7DkT2Tp", elem.MustGetString())
+
+	_, err = dicom.ReadDataSetInBytes([]byte("this is not a dicom file"), dicom.ReadOptions{})
+	assert.Error(t, err)
+}
+
+func TestWritePrivateTags(t *testing.T) {
+	creator := dicomtag.Tag{Group: 0x0029, Element: 0x0010}
+	privateTag := dicomtag.Tag{Group: 0x0029, Element: 0x1008}
+	orphanTag := dicomtag.Tag{Group: 0x0029, Element: 0x2008}
+	newPrivateElement := func(tag dicomtag.Tag, value string) *dicom.Element {
+		return &dicom.Element{Tag: tag, VR: "LO", Value: []interface{}{value}}
+	}
+	ds := dicom.DataSet{Elements: []*dicom.Element{
+		dicom.MustNewElement(dicomtag.TransferSyntaxUID, dicomuid.ExplicitVRLittleEndian),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPClassUID, "1.2.840.10008.5.1.4.1.1.2"),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPInstanceUID, "1.2.3.4.5.6.7"),
+		dicom.MustNewElement(dicomtag.PatientName, "Alice^Doe"),
+		newPrivateElement(creator, "SIEMENS CSA HEADER"),
+		newPrivateElement(privateTag, "IMAGE NUM 4"),
+		newPrivateElement(orphanTag, "no creator"),
+		dicom.MustNewElement(dicomtag.ReferencedSeriesSequence,
+			dicom.MustNewElement(dicomtag.Item,
+				newPrivateElement(creator, "SIEMENS CSA HEADER"),
+				newPrivateElement(privateTag, "nested"))),
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, dicom.WriteDataSet(&buf, &ds))
+	ds2, err := dicom.ReadDataSet(&buf, dicom.ReadOptions{})
+	require.NoError(t, err)
+	elem, err := ds2.FindElementByTag(creator)
+	require.NoError(t, err)
+	assert.Equal(t, "SIEMENS CSA HEADER", elem.MustGetString())
+	elem, err = ds2.FindElementByTag(privateTag)
+	require.NoError(t, err)
+	assert.Equal(t, "IMAGE NUM 4", elem.MustGetString())
+	_, err = ds2.FindElementByTag(orphanTag)
+	assert.Error(t, err, "private element without a creator must be dropped")
+	elem, err = ds2.FindElementByTag(dicomtag.ReferencedSeriesSequence)
+	require.NoError(t, err)
+	item := elem.Value[0].(*dicom.Element)
+	elem, err = dicom.FindElementByTag(item.GetElements(), privateTag)
+	require.NoError(t, err)
+	assert.Equal(t, "nested", elem.MustGetString())
+
+	buf.Reset()
+	require.NoError(t, dicom.WriteDataSet(&buf, &ds, dicom.StripPrivateTags()))
+	ds2, err = dicom.ReadDataSet(&buf, dicom.ReadOptions{})
+	require.NoError(t, err)
+	for _, elem := range ds2.Elements {
+		assert.False(t, dicomtag.IsPrivate(elem.Tag.Group), elem.String())
+	}
+	elem, err = ds2.FindElementByTag(dicomtag.ReferencedSeriesSequence)
+	require.NoError(t, err)
+	assert.Empty(t, elem.Value[0].(*dicom.Element).Value)
+}
+
+func TestReadSpecificCharacterSetInItem(t *testing.T) {
+	ds := dicom.DataSet{Elements: []*dicom.Element{
+		dicom.MustNewElement(dicomtag.TransferSyntaxUID, dicomuid.ExplicitVRLittleEndian),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPClassUID, "1.2.840.10008.5.1.4.31"),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPInstanceUID, "1.2.3.4.5.6.7"),
+		dicom.MustNewElement(dicomtag.SpecificCharacterSet, "ISO_IR 100"),
+		dicom.MustNewElement(dicomtag.RequestAttributesSequence,
+			dicom.MustNewElement(dicomtag.Item,
+				dicom.MustNewElement(dicomtag.SpecificCharacterSet, "ISO_IR 144"),
+				dicom.MustNewElement(dicomtag.RequestedProcedureDescription, "Иванов")),
+			dicom.MustNewElement(dicomtag.Item,
+				dicom.MustNewElement(dicomtag.RequestedProcedureDescription, "Müller"))),
+		dicom.MustNewElement(dicomtag.PatientName, "Müller"),
+	}}
+	var buf bytes.Buffer
+	require.NoError(t, dicom.WriteDataSet(&buf, &ds))
+	// "Иванов" in ISO-8859-5, "Müller" in ISO-8859-1.
+	assert.Contains(t, buf.String(), "\xb8\xd2\xd0\xdd\xde\xd2")
+	assert.Contains(t, buf.String(), "M\xfcller")
+	ds2, err := dicom.ReadDataSet(&buf, dicom.ReadOptions{})
+	require.NoError(t, err)
+
+	seq, err := ds2.FindElementByTag(dicomtag.RequestAttributesSequence)
+	require.NoError(t, err)
+	require.Len(t, seq.Value, 2)
+	elem, err := dicom.FindElementByTag(seq.Value[0].(*dicom.Element).GetElements(), dicomtag.RequestedProcedureDescription)
+	require.NoError(t, err)
+	assert.Equal(t, "Иванов", elem.MustGetString())
+	elem, err = dicom.FindElementByTag(seq.Value[1].(*dicom.Element).GetElements(), dicomtag.RequestedProcedureDescription)
+	require.NoError(t, err)
+	assert.Equal(t, "Müller", elem.MustGetString())
+
+	// The charset of the first item doesn't leak to the rest of the file.
+	elem, err = ds2.FindElementByTag(dicomtag.PatientName)
+	require.NoError(t, err)
+	assert.Equal(t, "Müller", elem.MustGetString())
+}
+
+func TestWriteSpecificCharacterSet(t *testing.T) {
+	newDataSet := func(elems ...*dicom.Element) *dicom.DataSet {
+		return &dicom.DataSet{Elements: append([]*dicom.Element{
+			dicom.MustNewElement(dicomtag.TransferSyntaxUID, dicomuid.ExplicitVRLittleEndian),
+			dicom.MustNewElement(dicomtag.MediaStorageSOPClassUID, "1.2.840.10008.5.1.4.1.1.7"),
+			dicom.MustNewElement(dicomtag.MediaStorageSOPInstanceUID, "1.2.3.4.5.6.7"),
+		}, elems...)}
+	}
+	readBack := func(t *testing.T, data []byte) *dicom.DataSet {
+		ds, err := dicom.ReadDataSet(bytes.NewReader(data), dicom.ReadOptions{})
+		require.NoError(t, err)
+		return ds
+	}
+
+	t.Run("Cyrillic", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, dicom.WriteDataSet(&buf, newDataSet(
+			dicom.MustNewElement(dicomtag.SpecificCharacterSet, "ISO_IR 144"),
+			dicom.MustNewElement(dicomtag.PatientName, "Иванов^Иван"))))
+		assert.Contains(t, buf.String(), "\xb8\xd2\xd0\xdd\xde\xd2^\xb8\xd2\xd0\xdd")
+		elem, err := readBack(t, buf.Bytes()).FindElementByTag(dicomtag.PatientName)
+		require.NoError(t, err)
+		assert.Equal(t, "Иванов^Иван", elem.MustGetString())
+	})
+	t.Run("ISO2022", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, dicom.WriteDataSet(&buf, newDataSet(
+			dicom.MustNewElement(dicomtag.SpecificCharacterSet, "", "ISO 2022 IR 87"),
+			dicom.MustNewElement(dicomtag.PatientName, "Yamada^Tarou=山田^太郎=やまだ^たろう"))))
+		// P3.5 H.3.1.
+		assert.Contains(t, buf.String(), "Yamada^Tarou=\x1b$B;3ED\x1b(B^\x1b$BB@O:\x1b(B=\x1b$B$d$^$@\x1b(B^\x1b$B$?$m$&\x1b(B")
+		elem, err := readBack(t, buf.Bytes()).FindElementByTag(dicomtag.PatientName)
+		require.NoError(t, err)
+		assert.Equal(t, "Yamada^Tarou=山田^太郎=やまだ^たろう", elem.MustGetString())
+	})
+	t.Run("Unencodable", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := dicom.WriteDataSet(&buf, newDataSet(
+			dicom.MustNewElement(dicomtag.SpecificCharacterSet, "ISO_IR 100"),
+			dicom.MustNewElement(dicomtag.PatientName, "Иванов")))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "PatientName")
+		// An unsupported character set can't be written in.
+		err = dicom.WriteDataSet(&buf, newDataSet(
+			dicom.MustNewElement(dicomtag.SpecificCharacterSet, "ISO_IR 999"),
+			dicom.MustNewElement(dicomtag.PatientName, "Müller")))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "ISO_IR 999")
+		buf.Reset()
+		require.NoError(t, dicom.WriteDataSet(&buf, newDataSet(
+			dicom.MustNewElement(dicomtag.SpecificCharacterSet, "ISO_IR 999"),
+			dicom.MustNewElement(dicomtag.PatientName, "Muller"))))
+	})
+	t.Run("NoSpecificCharacterSet", func(t *testing.T) {
+		// Without SpecificCharacterSet, non-ASCII strings are written in
+		// UTF-8.
+		var buf bytes.Buffer
+		require.NoError(t, dicom.WriteDataSet(&buf, newDataSet(
+			dicom.MustNewElement(dicomtag.PatientName, "Иванов"))))
+		ds := readBack(t, buf.Bytes())
+		elem, err := ds.FindElementByTag(dicomtag.SpecificCharacterSet)
+		require.NoError(t, err)
+		assert.Equal(t, "ISO_IR 192", elem.MustGetString())
+		elem, err = ds.FindElementByTag(dicomtag.PatientName)
+		require.NoError(t, err)
+		assert.Equal(t, "Иванов", elem.MustGetString())
+
+		buf.Reset()
+		require.NoError(t, dicom.WriteDataSet(&buf, newDataSet(
+			dicom.MustNewElement(dicomtag.PatientName, "Ivanov"))))
+		assert.False(t, readBack(t, buf.Bytes()).Has(dicomtag.SpecificCharacterSet))
+	})
+	t.Run("CP1250Fix", func(t *testing.T) {
+		// Strings read with ReadOptions.CP1250Fix are written back in
+		// windows-1250 with the CP1250Fix option.
+		ds := newDataSet(
+			dicom.MustNewElement(dicomtag.SpecificCharacterSet, "ISO_IR 100"),
+			dicom.MustNewElement(dicomtag.PatientName, "Łukasz"))
+		var buf bytes.Buffer
+		require.Error(t, dicom.WriteDataSet(&buf, ds))
+		buf.Reset()
+		require.NoError(t, dicom.WriteDataSet(&buf, ds, dicom.CP1250Fix()))
+		assert.Contains(t, buf.String(), "\xa3ukasz")
+		ds, err := dicom.ReadDataSetInBytes(buf.Bytes(), dicom.ReadOptions{CP1250Fix: true})
+		require.NoError(t, err)
+		elem, err := ds.FindElementByTag(dicomtag.PatientName)
+		require.NoError(t, err)
+		assert.Equal(t, "Łukasz", elem.MustGetString())
+	})
+	t.Run("FallbackToUTF8", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, dicom.WriteDataSet(&buf, newDataSet(
+			dicom.MustNewElement(dicomtag.SpecificCharacterSet, "ISO_IR 100"),
+			dicom.MustNewElement(dicomtag.PatientName, "Иванов")), dicom.FallbackToUTF8()))
+		ds := readBack(t, buf.Bytes())
+		elem, err := ds.FindElementByTag(dicomtag.SpecificCharacterSet)
+		require.NoError(t, err)
+		assert.Equal(t, "ISO_IR 192", elem.MustGetString())
+		elem, err = ds.FindElementByTag(dicomtag.PatientName)
+		require.NoError(t, err)
+		assert.Equal(t, "Иванов", elem.MustGetString())
+
+		// SpecificCharacterSet is added if missing.
+		buf.Reset()
+		require.NoError(t, dicom.WriteDataSet(&buf, newDataSet(
+			dicom.MustNewElement(dicomtag.PatientName, "Müller")), dicom.FallbackToUTF8()))
+		ds = readBack(t, buf.Bytes())
+		elem, err = ds.FindElementByTag(dicomtag.SpecificCharacterSet)
+		require.NoError(t, err)
+		assert.Equal(t, "ISO_IR 192", elem.MustGetString())
+		elem, err = ds.FindElementByTag(dicomtag.PatientName)
+		require.NoError(t, err)
+		assert.Equal(t, "Müller", elem.MustGetString())
+	})
+}
+
+func TestWriteAllVRs(t *testing.T) {
+	elems := []*dicom.Element{
+		dicom.MustNewElement(dicomtag.FrameIncrementPointer, dicomtag.FrameTime, dicomtag.FrameTimeVector),
+		dicom.MustNewElement(dicomtag.RetrieveURL, "https://example.com/studies/1.2.3"),
+		{Tag: dicomtag.Tag{Group: 0x0072, Element: 0x0081}, VR: "OL", Value: []interface{}{uint32(1), uint32(0xffffffff)}},
+		{Tag: dicomtag.Tag{Group: 0x0072, Element: 0x0082}, VR: "SV", Value: []interface{}{int64(-1), int64(1 << 62)}},
+		{Tag: dicomtag.Tag{Group: 0x0072, Element: 0x0083}, VR: "UV", Value: []interface{}{uint64(1<<64 - 1)}},
+		{Tag: dicomtag.Tag{Group: 0x0072, Element: 0x0084}, VR: "UC", Value: []interface{}{"foo", "bar"}},
+		{Tag: dicomtag.Tag{Group: 0x0072, Element: 0x0085}, VR: "UN", Value: []interface{}{[]byte{1, 2, 3}}},
+		// Not ExtendedOffsetTable, which WriteDataSet recomputes from the pixel data.
+		{Tag: dicomtag.Tag{Group: 0x0072, Element: 0x0086}, VR: "OV", Value: []interface{}{uint64(0), uint64(1 << 40)}},
+	}
+	for _, uid := range []string{dicomuid.ExplicitVRLittleEndian, dicomuid.ExplicitVRBigEndian, dicomuid.ImplicitVRLittleEndian} {
+		t.Run(uid, func(t *testing.T) {
+			ds := &dicom.DataSet{Elements: append([]*dicom.Element{
+				dicom.MustNewElement(dicomtag.TransferSyntaxUID, uid),
+				dicom.MustNewElement(dicomtag.MediaStorageSOPClassUID, "1.2.840.10008.5.1.4.1.1.77.1.6"),
+				dicom.MustNewElement(dicomtag.MediaStorageSOPInstanceUID, "1.2.3.4.5.6.7"),
+			}, elems...)}
+			var buf bytes.Buffer
+			require.NoError(t, dicom.WriteDataSet(&buf, ds))
+			ds2, err := dicom.ReadDataSet(&buf, dicom.ReadOptions{})
+			require.NoError(t, err)
+			for _, elem := range elems {
+				if uid == dicomuid.ImplicitVRLittleEndian {
+					if _, err := dicomtag.Find(elem.Tag); err != nil {
+						// The VR is unknown without the dictionary.
+						continue
+					}
+				}
+				elem2, err := ds2.FindElementByTag(elem.Tag)
+				require.NoError(t, err)
+				assert.Equal(t, elem.VR, elem2.VR)
+				if elem.VR == "UN" {
+					// Padded to even length.
+					assert.Equal(t, []interface{}{[]byte{1, 2, 3, 0}}, elem2.Value)
+					continue
+				}
+				assert.Equal(t, elem.Value, elem2.Value, dicomtag.DebugString(elem.Tag))
+			}
+		})
+	}
+}
@@ -5,10 +5,13 @@ package dicomio
 import (
 	"bufio"
 	"bytes"
+	"compress/flate"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"math"
+	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/text/encoding"
 )
@@ -41,6 +44,11 @@ type Encoder struct {
 
 	// Stack of old transfer syntaxes. Used by {Push,Pop}TransferSyntax.
 	oldTransferSyntaxes []transferSyntaxStackEntry
+
+	// For encoding utf-8 strings into the charset of the DICOM file.
+	codingSystem CodingSystem
+	// Stack of old coding systems. Used by {Push,Pop}CodingSystem.
+	oldCodingSystems []CodingSystem
 }
 
 // NewBytesEncoder creates a new Encoder that writes to an in-memory buffer. The
@@ -111,6 +119,55 @@ func (e *Encoder) PopTransferSyntax() {
 	e.oldTransferSyntaxes = e.oldTransferSyntaxes[:len(e.oldTransferSyntaxes)-1]
 }
 
+// SetCodingSystem overrides the default (7bit ASCII) encoder used by
+// EncodeString.
+func (e *Encoder) SetCodingSystem(cs CodingSystem) {
+	e.codingSystem = cs
+}
+
+// CodingSystem returns the coding system currently used for encoding strings.
+func (e *Encoder) CodingSystem() CodingSystem {
+	return e.codingSystem
+}
+
+// PushCodingSystem saves the current coding system. Like the Decoder
+// counterpart, it is used to scope a SpecificCharacterSet to a sequence item.
+func (e *Encoder) PushCodingSystem() {
+	e.oldCodingSystems = append(e.oldCodingSystems, e.codingSystem)
+}
+
+// PopCodingSystem restores the coding system active at the last call to
+// PushCodingSystem().
+func (e *Encoder) PopCodingSystem() {
+	e.codingSystem = e.oldCodingSystems[len(e.oldCodingSystems)-1]
+	e.oldCodingSystems = e.oldCodingSystems[:len(e.oldCodingSystems)-1]
+}
+
+// EncodeString converts a utf-8 string into the current coding system. It
+// returns an error if the string contains a character that the coding system
+// can't represent.
+func (e *Encoder) EncodeString(s string) ([]byte, error) {
+	if e.codingSystem.Encoder == nil {
+		// Default character repertoire. P3.5 6.1.2.1.
+		for i := 0; i < len(s); i++ {
+			if s[i] < utf8.RuneSelf {
+				continue
+			}
+			if e.codingSystem.unsupported != "" {
+				return nil, fmt.Errorf("%q contains a non-ASCII character, but SpecificCharacterSet '%s' is not supported for writing",
+					s, e.codingSystem.unsupported)
+			}
+			return nil, fmt.Errorf("%q contains a non-ASCII character, but SpecificCharacterSet is not set", s)
+		}
+		return []byte(s), nil
+	}
+	b, err := e.codingSystem.Encoder.Bytes([]byte(s))
+	if err != nil {
+		return nil, fmt.Errorf("%q can't be encoded in SpecificCharacterSet: %v", s, err)
+	}
+	return b, nil
+}
+
 // SetError sets the error to be reported by future Error() calls.  If called
 // multiple times with different errors, Error() will return one of them, but
 // exactly which is unspecified.
@@ -161,6 +218,12 @@ func (e *Encoder) WriteUInt32(v uint32) {
 	}
 }
 
+func (e *Encoder) WriteUInt64(v uint64) {
+	if err := binary.Write(e.out, e.bo, &v); err != nil {
+		e.SetError(err)
+	}
+}
+
 func (e *Encoder) WriteInt16(v int16) {
 	if err := binary.Write(e.out, e.bo, &v); err != nil {
 		e.SetError(err)
@@ -173,6 +236,12 @@ func (e *Encoder) WriteInt32(v int32) {
 	}
 }
 
+func (e *Encoder) WriteInt64(v int64) {
+	if err := binary.Write(e.out, e.bo, &v); err != nil {
+		e.SetError(err)
+	}
+}
+
 func (e *Encoder) WriteFloat32(v float32) {
 	if err := binary.Write(e.out, e.bo, &v); err != nil {
 		e.SetError(err)
@@ -242,6 +311,8 @@ type Decoder struct {
 
 	// Stack of old transfer syntaxes. Used by {Push,Pop}TransferSyntax.
 	oldTransferSyntaxes []transferSyntaxStackEntry
+	// Stack of old coding systems. Used by {Push,Pop}CodingSystem.
+	oldCodingSystems []CodingSystem
 	// Stack of old limits. Used by {Push,Pop}Limit.
 	// INVARIANT: oldLimits[] store values in decreasing order.
 	stateStack []stackEntry
@@ -328,6 +399,40 @@ func (d *Decoder) SetCodingSystem(cs CodingSystem) {
 	d.codingSystem = cs
 }
 
+// CodingSystem returns the coding system currently used for decoding strings.
+func (d *Decoder) CodingSystem() CodingSystem {
+	return d.codingSystem
+}
+
+// PushCodingSystem saves the current coding system. A SpecificCharacterSet
+// element found inside a sequence item applies only to that item (P3.5
+// 7.5.1), so the reader calls PushCodingSystem when it enters an item, and
+// PopCodingSystem when it leaves it. The current coding system is unchanged
+// until the next SetCodingSystem call.
+func (d *Decoder) PushCodingSystem() {
+	d.oldCodingSystems = append(d.oldCodingSystems, d.codingSystem)
+}
+
+// PopCodingSystem restores the coding system active at the last call to
+// PushCodingSystem().
+func (d *Decoder) PopCodingSystem() {
+	d.codingSystem = d.oldCodingSystems[len(d.oldCodingSystems)-1]
+	d.oldCodingSystems = d.oldCodingSystems[:len(d.oldCodingSystems)-1]
+}
+
+// StartInflating makes the decoder read the rest of the input through a raw
+// DEFLATE (RFC 1951) decompressor. It is used for the Deflated Explicit VR
+// Little Endian transfer syntax, where everything that follows the file meta
+// group is compressed. PS3.5 A.5.
+//
+// After this call, BytesRead() counts the decompressed bytes.
+//
+// REQUIRES: no limit is pushed by PushLimit.
+func (d *Decoder) StartInflating() {
+	doassert(len(d.stateStack) == 0)
+	d.in = bufio.NewReader(flate.NewReader(d.in))
+}
+
 // PopTransferSyntax restores the encoding format active before the last call to
 // PushTransferSyntax().
 func (d *Decoder) PopTransferSyntax() {
@@ -415,6 +520,20 @@ func (d *Decoder) EOF() bool {
 	return len(data) == 0
 }
 
+// Peek returns the next "length" bytes without advancing the read pointer. It
+// returns fewer bytes if the input, or the current limit, ends earlier. The
+// result is valid only until the next read.
+func (d *Decoder) Peek(length int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if d.len() < int64(length) {
+		length = int(d.len())
+	}
+	data, _ := d.in.Peek(length)
+	return data
+}
+
 // BytesRead returns the cumulative # of bytes read so far.
 func (d *Decoder) BytesRead() int64 { return d.pos }
 
@@ -450,6 +569,22 @@ func (d *Decoder) ReadInt32() (v int32) {
 	return v
 }
 
+func (d *Decoder) ReadUInt64() (v uint64) {
+	err := binary.Read(d, d.bo, &v)
+	if err != nil {
+		d.SetError(err)
+	}
+	return v
+}
+
+func (d *Decoder) ReadInt64() (v int64) {
+	err := binary.Read(d, d.bo, &v)
+	if err != nil {
+		d.SetError(err)
+	}
+	return v
+}
+
 func (d *Decoder) ReadUInt16() (v uint16) {
 	err := binary.Read(d, d.bo, &v)
 	if err != nil {
@@ -519,6 +654,49 @@ func (d *Decoder) ReadString(length int) string {
 	return internalReadString(d, d.codingSystem.Ideographic, length)
 }
 
+// ReadPersonName reads a value of VR PN. Each of the '='-delimited component
+// groups is decoded with its own coding system: alphabetic, ideographic, and
+// phonetic, in this order. P3.5 6.2.1.
+func (d *Decoder) ReadPersonName(length int) string {
+	cs := d.codingSystem
+	if !cs.iso2022 && cs.Alphabetic == cs.Ideographic && cs.Ideographic == cs.Phonetic {
+		// A single-byte or UTF-8 charset. Note that the delimiters
+		// can't be found before decoding since they may appear in the
+		// second byte of a GB18030 character.
+		return internalReadString(d, cs.Ideographic, length)
+	}
+	data := d.ReadBytes(length)
+	decoders := []*encoding.Decoder{cs.Alphabetic, cs.Ideographic, cs.Phonetic}
+	// The charsets are reset at each delimiter, including '^'. P3.5
+	// 6.1.2.5.3.
+	pieces, delims := splitISO2022(data, "\\=^")
+	var str strings.Builder
+	group := 0
+	for i, piece := range pieces {
+		sd := decoders[group]
+		if sd == nil || len(piece) == 0 {
+			str.Write(piece)
+		} else if decoded, err := sd.Bytes(piece); err != nil {
+			d.SetError(err)
+			return ""
+		} else {
+			str.Write(decoded)
+		}
+		if i < len(delims) {
+			str.WriteByte(delims[i])
+			switch delims[i] {
+			case '=':
+				if group < len(decoders)-1 {
+					group++
+				}
+			case '\\':
+				group = 0
+			}
+		}
+	}
+	return str.String()
+}
+
 func (d *Decoder) ReadBytes(length int) []byte {
 	if d.len() < int64(length) {
 		d.SetError(fmt.Errorf("ReadBytes: requested %d, available %d", length, d.len()))
@@ -9,7 +9,8 @@ import (
 	"golang.org/x/text/encoding/htmlindex"
 )
 
-// CodingSystem defines how a []byte is translated into a utf8 string.
+// CodingSystem defines how a []byte is translated into a utf8 string, and
+// vice versa.
 type CodingSystem struct {
 	// VR="PN" is the only place where we potentially use all three
 	// decoders.  For all other VR types, only Ideographic decoder is used.
@@ -22,6 +23,16 @@ type CodingSystem struct {
 	Alphabetic  *encoding.Decoder
 	Ideographic *encoding.Decoder
 	Phonetic    *encoding.Decoder
+
+	// Encoder converts a utf8 string into the charset when writing a file.
+	// If nil, only 7bit ASCII strings can be encoded.
+	Encoder *encoding.Encoder
+
+	// True if the decoders honor ISO 2022 escape sequences in a value.
+	iso2022 bool
+	// The SpecificCharacterSet value that isn't supported, in which case
+	// the decoders fall back to UTF-8, but Encoder is nil.
+	unsupported string
 }
 
 // CodingSystemType defines the where the coding system is going to be
@@ -83,21 +94,21 @@ var htmlEncodingNames = map[string]string{
 	"IBM866":          "ibm866",       // IBM866
 }
 
-// getCustomDecoder возвращает кастомный декодер для специальных кодировок
-func getCustomDecoder(encodingName string) *encoding.Decoder {
+// getCustomEncoding возвращает кастомную кодировку для специальных кодировок
+func getCustomEncoding(encodingName string) encoding.Encoding {
 	switch encodingName {
 	case "iso-8859-5":
-		return charmap.ISO8859_5.NewDecoder()
+		return charmap.ISO8859_5
 	case "koi8-r":
-		return charmap.KOI8R.NewDecoder()
+		return charmap.KOI8R
 	case "koi8-u":
-		return charmap.KOI8U.NewDecoder()
+		return charmap.KOI8U
 	case "windows-1251":
-		return charmap.Windows1251.NewDecoder()
+		return charmap.Windows1251
 	case "windows-1250":
-		return charmap.Windows1250.NewDecoder()
+		return charmap.Windows1250
 	case "ibm866":
-		return charmap.CodePage866.NewDecoder()
+		return charmap.CodePage866
 	default:
 		return nil
 	}
@@ -107,9 +118,26 @@ func getCustomDecoder(encodingName string) *encoding.Decoder {
 // "ISO-IR 100" to golang decoder. It will return nil, nil for the default (7bit
 // ASCII) encoding. Cf. P3.2
 // D.6.2. http://dicom.nema.org/medical/dicom/2016d/output/chtml/part02/sect_D.6.2.html
+//
+// If the names are defined terms with code extensions, such as "ISO 2022 IR
+// 87", the decoders switch charsets on the escape sequences found in a value.
+// P3.5 6.1.2.5.
+//
+// The returned CodingSystem also has the encoder for the reverse conversion,
+// used when writing a file.
 func ParseSpecificCharacterSet(encodingNames []string, CP1250Fix bool) (CodingSystem, error) {
+	normalizedNames := make([]string, len(encodingNames))
+	for i, name := range encodingNames {
+		normalizedNames[i] = strings.Join(strings.Fields(name), " ")
+	}
+	if cs, ok := newISO2022CodingSystem(normalizedNames); ok {
+		dicomlog.Vprintf(2, "dicom.ParseSpecificCharacterSet: Using code extensions %v", normalizedNames)
+		return cs, nil
+	}
 	var decoders []*encoding.Decoder
-	for _, name := range encodingNames {
+	var encoder *encoding.Encoder
+	unsupported := ""
+	for i, name := range encodingNames {
 		if CP1250Fix && name == "ISO_IR 100" {
 			name = "CP1250HACK"
 		}
@@ -119,13 +147,14 @@ func ParseSpecificCharacterSet(encodingNames []string, CP1250Fix bool) (CodingSy
 		// Замена всех подряд идущих пробелов на один пробел
 		normalizedName = strings.Join(strings.Fields(normalizedName), " ")
 
-		var c *encoding.Decoder
+		var c encoding.Encoding
+		fallback := false
 		dicomlog.Vprintf(2, "dicom.ParseSpecificCharacterSet: Using coding system %s", normalizedName)
 
 		if htmlName, ok := htmlEncodingNames[normalizedName]; !ok {
 			// Попробуем найти кодировку по альтернативным именам
-			if altDecoder := tryAlternativeEncodings(normalizedName); altDecoder != nil {
-				c = altDecoder
+			if altEncoding := tryAlternativeEncodings(normalizedName); altEncoding != nil {
+				c = altEncoding
 				dicomlog.Vprintf(2, "dicom.ParseSpecificCharacterSet: Found alternative encoding for %s", normalizedName)
 			} else {
 				// TODO(saito) Support more encodings.
@@ -133,76 +162,90 @@ func ParseSpecificCharacterSet(encodingNames []string, CP1250Fix bool) (CodingSy
 				// Не возвращаем ошибку, а используем UTF-8 как fallback
 				d, err := htmlindex.Get("utf-8")
 				if err == nil {
-					c = d.NewDecoder()
+					c = d
+					fallback = true
 				}
 			}
 		} else {
 			if htmlName != "" {
-				// Сначала пробуем кастомный декодер
-				if customDecoder := getCustomDecoder(htmlName); customDecoder != nil {
-					c = customDecoder
+				// Сначала пробуем кастомную кодировку
+				if customEncoding := getCustomEncoding(htmlName); customEncoding != nil {
+					c = customEncoding
 				} else {
-					// Если кастомного декодера нет, используем htmlindex
+					// Если кастомной кодировки нет, используем htmlindex
 					d, err := htmlindex.Get(htmlName)
 					if err != nil {
 						dicomlog.Vprintf(1, "dicom.ParseSpecificCharacterSet: Encoding %s (for %s) not found in htmlindex, trying custom decoder", htmlName, normalizedName)
-						// Попробуем кастомный декодер еще раз с оригинальным именем
-						if customDecoder := getCustomDecoder(normalizedName); customDecoder != nil {
-							c = customDecoder
+						// Попробуем кастомную кодировку еще раз с оригинальным именем
+						if customEncoding := getCustomEncoding(normalizedName); customEncoding != nil {
+							c = customEncoding
 						} else {
 							// Fallback to UTF-8
 							fallbackD, fallbackErr := htmlindex.Get("utf-8")
 							if fallbackErr == nil {
-								c = fallbackD.NewDecoder()
+								c = fallbackD
+								fallback = true
 								dicomlog.Vprintf(1, "dicom.ParseSpecificCharacterSet: Using UTF-8 as fallback for %s", normalizedName)
 							}
 						}
 					} else {
-						c = d.NewDecoder()
+						c = d
 					}
 				}
 			}
 		}
-		decoders = append(decoders, c)
+		if c == nil {
+			decoders = append(decoders, nil)
+			continue
+		}
+		decoders = append(decoders, c.NewDecoder())
+		// Multiple values without code extensions aren't allowed by the
+		// standard. Write in the first charset. Writing in UTF-8 when
+		// it's unsupported would make the file lie about its encoding.
+		if i == 0 && fallback {
+			unsupported = normalizedName
+		} else if encoder == nil && unsupported == "" {
+			encoder = c.NewEncoder()
+		}
 	}
 
 	if len(decoders) == 0 {
-		return CodingSystem{nil, nil, nil}, nil
+		return CodingSystem{}, nil
 	}
 	if len(decoders) == 1 {
-		return CodingSystem{decoders[0], decoders[0], decoders[0]}, nil
+		return CodingSystem{Alphabetic: decoders[0], Ideographic: decoders[0], Phonetic: decoders[0], Encoder: encoder, unsupported: unsupported}, nil
 	}
 	if len(decoders) == 2 {
-		return CodingSystem{decoders[0], decoders[1], decoders[1]}, nil
+		return CodingSystem{Alphabetic: decoders[0], Ideographic: decoders[1], Phonetic: decoders[1], Encoder: encoder, unsupported: unsupported}, nil
 	}
-	return CodingSystem{decoders[0], decoders[1], decoders[2]}, nil
+	return CodingSystem{Alphabetic: decoders[0], Ideographic: decoders[1], Phonetic: decoders[2], Encoder: encoder, unsupported: unsupported}, nil
 }
 
 // tryAlternativeEncodings пытается найти кодировку по альтернативным именам
-func tryAlternativeEncodings(name string) *encoding.Decoder {
+func tryAlternativeEncodings(name string) encoding.Encoding {
 	upperName := strings.ToUpper(name)
 
 	// Список альтернативных имен для кириллических кодировок
-	alternatives := map[string]*encoding.Decoder{
-		"CYRILLIC":    charmap.ISO8859_5.NewDecoder(),
-		"ISO-8859-5":  charmap.ISO8859_5.NewDecoder(),
-		"ISO8859-5":   charmap.ISO8859_5.NewDecoder(),
-		"KOI8R":       charmap.KOI8R.NewDecoder(),
-		"KOI-8-R":     charmap.KOI8R.NewDecoder(),
-		"KOI8U":       charmap.KOI8U.NewDecoder(),
-		"KOI-8-U":     charmap.KOI8U.NewDecoder(),
-		"WIN-1251":    charmap.Windows1251.NewDecoder(),
-		"WIN1251":     charmap.Windows1251.NewDecoder(),
-		"WINDOWS1251": charmap.Windows1251.NewDecoder(),
-		"CP-1251":     charmap.Windows1251.NewDecoder(),
-		"CP-866":      charmap.CodePage866.NewDecoder(),
-		"CP866":       charmap.CodePage866.NewDecoder(),
-		"DOS-866":     charmap.CodePage866.NewDecoder(),
-		"IBM-866":     charmap.CodePage866.NewDecoder(),
+	alternatives := map[string]encoding.Encoding{
+		"CYRILLIC":    charmap.ISO8859_5,
+		"ISO-8859-5":  charmap.ISO8859_5,
+		"ISO8859-5":   charmap.ISO8859_5,
+		"KOI8R":       charmap.KOI8R,
+		"KOI-8-R":     charmap.KOI8R,
+		"KOI8U":       charmap.KOI8U,
+		"KOI-8-U":     charmap.KOI8U,
+		"WIN-1251":    charmap.Windows1251,
+		"WIN1251":     charmap.Windows1251,
+		"WINDOWS1251": charmap.Windows1251,
+		"CP-1251":     charmap.Windows1251,
+		"CP-866":      charmap.CodePage866,
+		"CP866":       charmap.CodePage866,
+		"DOS-866":     charmap.CodePage866,
+		"IBM-866":     charmap.CodePage866,
 	}
 
-	if decoder, exists := alternatives[upperName]; exists {
-		return decoder
+	if enc, exists := alternatives[upperName]; exists {
+		return enc
 	}
 
 	return nil
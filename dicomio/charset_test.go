@@ -0,0 +1,115 @@
+package dicomio_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/msz-kp/go-dicom/dicomio"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeString(t *testing.T, encodingNames []string, vr string, data string) string {
+	cs, err := dicomio.ParseSpecificCharacterSet(encodingNames, false)
+	require.NoError(t, err)
+	d := dicomio.NewBytesDecoder([]byte(data), binary.LittleEndian, dicomio.ExplicitVR)
+	d.SetCodingSystem(cs)
+	var s string
+	if vr == "PN" {
+		s = d.ReadPersonName(len(data))
+	} else {
+		s = d.ReadString(len(data))
+	}
+	require.NoError(t, d.Finish())
+	return s
+}
+
+// Examples from P3.5 Annex H, I, J.
+func TestISO2022PersonName(t *testing.T) {
+	tests := []struct {
+		names    []string
+		data     string
+		expected string
+	}{
+		{[]string{"", "ISO 2022 IR 87"},
+			"Yamada^Tarou=\x1b$B;3ED\x1b(B^\x1b$BB@O:\x1b(B=\x1b$B$d$^$@\x1b(B^\x1b$B$?$m$&\x1b(B",
+			"Yamada^Tarou=山田^太郎=やまだ^たろう"},
+		{[]string{"ISO 2022 IR 13", "ISO 2022 IR 87"},
+			"\xd4\xcf\xc0\xde^\xc0\xdb\xb3=\x1b$B;3ED\x1b(J^\x1b$BB@O:\x1b(J=\x1b$B$d$^$@\x1b(J^\x1b$B$?$m$&\x1b(J",
+			"ﾔﾏﾀﾞ^ﾀﾛｳ=山田^太郎=やまだ^たろう"},
+		{[]string{"", "ISO 2022 IR 149"},
+			"Hong^Gildong=\x1b$)C\xfb\xf3^\x1b$)C\xd1\xce\xd4\xd7=\x1b$)C\xc8\xab^\x1b$)C\xb1\xe6\xb5\xbf",
+			"Hong^Gildong=洪^吉洞=홍^길동"},
+		{[]string{"", "ISO 2022 IR 58"},
+			"Zhang^XiaoDong=\x1b$)A\xd5\xc5^\x1b$)A\xd0\xa1\xb6\xab=",
+			"Zhang^XiaoDong=张^小东="},
+		// Multiple values. The second value starts in the initial charset.
+		{[]string{"", "ISO 2022 IR 87"},
+			"\x1b$B;3ED\x1b(B\\Yamada",
+			"山田\\Yamada"},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.expected, decodeString(t, test.names, "PN", test.data), "%v", test.names)
+	}
+}
+
+func TestISO2022String(t *testing.T) {
+	// Charsets switched in the middle of a value.
+	require.Equal(t, "Patient 山田 (やまだ)",
+		decodeString(t, []string{"", "ISO 2022 IR 87"}, "LO", "Patient \x1b$B;3ED\x1b(B (\x1b$B$d$^$@\x1b(B)"))
+	require.Equal(t, "JIS X 0212: 丂",
+		decodeString(t, []string{"", "ISO 2022 IR 159"}, "LO", "JIS X 0212: \x1b$(D\x30\x21\x1b(B"))
+	// G1 is reset to ISO 8859-1 after the line break.
+	require.Equal(t, "Иван\r\nÈ",
+		decodeString(t, []string{"ISO 2022 IR 100", "ISO 2022 IR 144"}, "LT", "\x1b-L\xb8\xd2\xd0\xdd\r\n\xc8"))
+	// Strings without code extensions are decoded as before.
+	require.Equal(t, "Müller", decodeString(t, []string{"ISO_IR 100"}, "PN", "M\xfcller"))
+	require.Equal(t, "Wang^XiaoDong=王^小东=",
+		decodeString(t, []string{"GB18030"}, "PN", "Wang^XiaoDong=\xcd\xf5^\xd0\xa1\xb6\xab="))
+}
+
+func encodeString(t *testing.T, encodingNames []string, s string) (string, error) {
+	cs, err := dicomio.ParseSpecificCharacterSet(encodingNames, false)
+	require.NoError(t, err)
+	e := dicomio.NewBytesEncoder(binary.LittleEndian, dicomio.ExplicitVR)
+	e.SetCodingSystem(cs)
+	b, err := e.EncodeString(s)
+	return string(b), err
+}
+
+func TestEncodeString(t *testing.T) {
+	tests := []struct {
+		names    []string
+		s        string
+		expected string
+	}{
+		{[]string{"ISO_IR 100"}, "Müller", "M\xfcller"},
+		{[]string{"ISO_IR 144"}, "Иванов", "\xb8\xd2\xd0\xdd\xde\xd2"},
+		{[]string{"ISO_IR 192"}, "Иванов", "Иванов"},
+		{[]string{"ISO 2022 IR 13", "ISO 2022 IR 87"},
+			"ﾔﾏﾀﾞ^ﾀﾛｳ=山田^太郎=やまだ^たろう",
+			"\xd4\xcf\xc0\xde^\xc0\xdb\xb3=\x1b$B;3ED\x1b(J^\x1b$BB@O:\x1b(J=\x1b$B$d$^$@\x1b(J^\x1b$B$?$m$&\x1b(J"},
+		{[]string{"", "ISO 2022 IR 149"},
+			"Hong^Gildong=洪^吉洞=홍^길동",
+			"Hong^Gildong=\x1b$)C\xfb\xf3^\x1b$)C\xd1\xce\xd4\xd7=\x1b$)C\xc8\xab^\x1b$)C\xb1\xe6\xb5\xbf"},
+	}
+	for _, test := range tests {
+		s, err := encodeString(t, test.names, test.s)
+		require.NoError(t, err)
+		require.Equal(t, test.expected, s, "%v", test.names)
+	}
+
+	_, err := encodeString(t, []string{"ISO_IR 100"}, "Иванов")
+	require.Error(t, err)
+	_, err = encodeString(t, []string{"", "ISO 2022 IR 87"}, "홍")
+	require.Error(t, err)
+	// The default repertoire.
+	_, err = encodeString(t, nil, "Müller")
+	require.Error(t, err)
+	// An unsupported charset is decoded as UTF-8, but not written in it.
+	require.Equal(t, "Müller", decodeString(t, []string{"ISO_IR 999"}, "PN", "Müller"))
+	_, err = encodeString(t, []string{"ISO_IR 999"}, "Müller")
+	require.Error(t, err)
+	s, err := encodeString(t, []string{"ISO_IR 999"}, "Muller")
+	require.NoError(t, err)
+	require.Equal(t, "Muller", s)
+}
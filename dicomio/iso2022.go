@@ -0,0 +1,428 @@
+package dicomio
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+// iso2022Charset is a character set that an ISO 2022 escape sequence can
+// designate to G0 or G1. P3.3 C.12.1.1.2, P3.5 6.1.2.5.
+type iso2022Charset struct {
+	// Escape sequence that designates the charset, without the leading ESC.
+	escape string
+	// True if the charset is designated to G0 (bytes 0x21-0x7e), false if
+	// to G1 (bytes 0xa1-0xfe).
+	g0 bool
+	// # of bytes per character.
+	width int
+	// Converts a character in the EUC form, i.e., the high bit of each byte
+	// set and "prefix" prepended, from and to UTF-8. If nil, the charset is
+	// ASCII.
+	enc    encoding.Encoding
+	prefix byte
+}
+
+var (
+	iso2022IR6 = &iso2022Charset{escape: "(B", g0: true, width: 1}
+	// JIS X 0201 Romaji differs from ASCII only in YEN SIGN and OVERLINE.
+	// Decode it as ASCII, which is what most implementations do.
+	iso2022IR14  = &iso2022Charset{escape: "(J", g0: true, width: 1}
+	iso2022IR13  = &iso2022Charset{escape: ")I", width: 1, enc: japanese.EUCJP, prefix: 0x8e}
+	iso2022IR87  = &iso2022Charset{escape: "$B", g0: true, width: 2, enc: japanese.EUCJP}
+	iso2022IR159 = &iso2022Charset{escape: "$(D", g0: true, width: 2, enc: japanese.EUCJP, prefix: 0x8f}
+	iso2022IR149 = &iso2022Charset{escape: "$)C", width: 2, enc: korean.EUCKR}
+	iso2022IR58  = &iso2022Charset{escape: "$)A", width: 2, enc: simplifiedchinese.GBK}
+	iso2022IR100 = &iso2022Charset{escape: "-A", width: 1, enc: charmap.ISO8859_1}
+)
+
+// Charsets that may appear in SpecificCharacterSet with code extensions,
+// keyed by their defined terms.
+var iso2022Charsets = map[string]*iso2022Charset{
+	"ISO 2022 IR 6":   iso2022IR6,
+	"ISO 2022 IR 13":  iso2022IR13,
+	"ISO 2022 IR 87":  iso2022IR87,
+	"ISO 2022 IR 159": iso2022IR159,
+	"ISO 2022 IR 149": iso2022IR149,
+	"ISO 2022 IR 58":  iso2022IR58,
+	"ISO 2022 IR 100": iso2022IR100,
+	"ISO 2022 IR 101": {escape: "-B", width: 1, enc: charmap.ISO8859_2},
+	"ISO 2022 IR 109": {escape: "-C", width: 1, enc: charmap.ISO8859_3},
+	"ISO 2022 IR 110": {escape: "-D", width: 1, enc: charmap.ISO8859_4},
+	"ISO 2022 IR 144": {escape: "-L", width: 1, enc: charmap.ISO8859_5},
+	"ISO 2022 IR 127": {escape: "-G", width: 1, enc: charmap.ISO8859_6},
+	"ISO 2022 IR 126": {escape: "-F", width: 1, enc: charmap.ISO8859_7},
+	"ISO 2022 IR 138": {escape: "-H", width: 1, enc: charmap.ISO8859_8},
+	"ISO 2022 IR 148": {escape: "-M", width: 1, enc: charmap.ISO8859_9},
+	"ISO 2022 IR 166": {escape: "-T", width: 1, enc: charmap.Windows874},
+}
+
+// Escape sequences recognized in a value, in addition to those in
+// iso2022Charsets. "ESC $ @" designates JIS C 6226-1978, the predecessor of
+// JIS X 0208, and is still emitted by some old devices.
+var iso2022ExtraCharsets = []*iso2022Charset{
+	iso2022IR14,
+	{escape: "$@", g0: true, width: 2, enc: japanese.EUCJP},
+}
+
+const iso2022ESC = 0x1b
+
+// findISO2022Escape finds the charset designated by the escape sequence at the
+// start of "b". b[0] must be ESC. If "b" is a prefix of a known sequence, it
+// returns nil, -1. If the sequence is unknown, it returns nil, 0.
+func findISO2022Escape(b []byte) (*iso2022Charset, int) {
+	incomplete := false
+	match := func(cs *iso2022Charset) bool {
+		if bytes.HasPrefix(b[1:], []byte(cs.escape)) {
+			return true
+		}
+		if strings.HasPrefix(cs.escape, string(b[1:])) {
+			incomplete = true
+		}
+		return false
+	}
+	for _, cs := range iso2022Charsets {
+		if match(cs) {
+			return cs, 1 + len(cs.escape)
+		}
+	}
+	for _, cs := range iso2022ExtraCharsets {
+		if match(cs) {
+			return cs, 1 + len(cs.escape)
+		}
+	}
+	if incomplete {
+		return nil, -1
+	}
+	return nil, 0
+}
+
+// newISO2022CodingSystem creates a CodingSystem that honors the escape
+// sequences in a value, if every name in "encodingNames" is either empty or a
+// defined term with code extensions (those starting with "ISO 2022"). The
+// names must be normalized. It returns false otherwise.
+//
+// The first name defines the charsets active at the start of each value, and
+// after each delimiter. P3.5 6.1.2.5.3.
+func newISO2022CodingSystem(encodingNames []string) (CodingSystem, bool) {
+	var charsets []*iso2022Charset
+	for _, name := range encodingNames {
+		if name == "" {
+			continue
+		}
+		cs, ok := iso2022Charsets[name]
+		if !ok {
+			return CodingSystem{}, false
+		}
+		charsets = append(charsets, cs)
+	}
+	if len(charsets) == 0 {
+		return CodingSystem{}, false
+	}
+	g0, g1 := iso2022IR6, (*iso2022Charset)(nil)
+	if cs := iso2022Charsets[encodingNames[0]]; cs != nil && !cs.g0 {
+		g1 = cs
+		if cs == iso2022IR13 {
+			g0 = iso2022IR14
+		}
+	}
+	newDecoder := func() *encoding.Decoder {
+		return &encoding.Decoder{Transformer: &iso2022Decoder{initialG0: g0, initialG1: g1, g0: g0, g1: g1}}
+	}
+	return CodingSystem{
+		Alphabetic:  newDecoder(),
+		Ideographic: newDecoder(),
+		Phonetic:    newDecoder(),
+		Encoder:     &encoding.Encoder{Transformer: &iso2022Encoder{charsets: charsets, initialG0: g0, initialG1: g1, g0: g0, g1: g1}},
+		iso2022:     true,
+	}, true
+}
+
+// iso2022Decoder is a transform.Transformer that converts a string encoded
+// with ISO 2022 code extensions into UTF-8. It switches the charsets on escape
+// sequences, and resets them to the initial ones after each CR, LF, FF, TAB
+// and '\'.
+type iso2022Decoder struct {
+	initialG0, initialG1 *iso2022Charset
+	g0, g1               *iso2022Charset
+	// Decoders for iso2022Charset.enc, created lazily.
+	decoders map[*iso2022Charset]*encoding.Decoder
+}
+
+func (d *iso2022Decoder) Reset() {
+	d.g0, d.g1 = d.initialG0, d.initialG1
+}
+
+func (d *iso2022Decoder) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		b := src[nSrc]
+		if b == iso2022ESC {
+			cs, n := findISO2022Escape(src[nSrc:])
+			if n < 0 && !atEOF {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			if cs != nil {
+				if cs.g0 {
+					d.g0 = cs
+				} else {
+					d.g1 = cs
+				}
+				nSrc += n
+				continue
+			}
+			// Unknown escape sequence. Copy it as is.
+		}
+		cs := d.g0
+		if b >= 0x80 {
+			cs = d.g1
+			if cs == nil {
+				// No charset is designated to G1. Latin-1 is
+				// the best guess.
+				cs = iso2022IR100
+			}
+		} else if cs.width == 1 || b <= 0x20 || b == 0x7f {
+			// ASCII, including control characters and space
+			// that may appear in the middle of multi-byte text.
+			if nDst >= len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			dst[nDst] = b
+			nDst++
+			nSrc++
+			if strings.IndexByte("\r\n\f\t\\", b) >= 0 {
+				d.Reset()
+			}
+			continue
+		}
+		if nSrc+cs.width > len(src) {
+			if !atEOF {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			// Truncated character.
+			if nDst+utf8.RuneLen(utf8.RuneError) > len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			nDst += utf8.EncodeRune(dst[nDst:], utf8.RuneError)
+			nSrc = len(src)
+			break
+		}
+		var euc [3]byte
+		n := 0
+		if cs.prefix != 0 {
+			euc[n] = cs.prefix
+			n++
+		}
+		for i := 0; i < cs.width; i++ {
+			euc[n] = src[nSrc+i] | 0x80
+			n++
+		}
+		dec := d.decoder(cs)
+		dec.Reset()
+		m, _, err := dec.Transform(dst[nDst:], euc[:n], true)
+		if err != nil {
+			if err == transform.ErrShortDst {
+				return nDst, nSrc, err
+			}
+			if nDst+utf8.RuneLen(utf8.RuneError) > len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			m = utf8.EncodeRune(dst[nDst:], utf8.RuneError)
+		}
+		nDst += m
+		nSrc += cs.width
+	}
+	return nDst, nSrc, nil
+}
+
+func (d *iso2022Decoder) decoder(cs *iso2022Charset) *encoding.Decoder {
+	if d.decoders == nil {
+		d.decoders = map[*iso2022Charset]*encoding.Decoder{}
+	}
+	dec, ok := d.decoders[cs]
+	if !ok {
+		dec = cs.enc.NewDecoder()
+		d.decoders[cs] = dec
+	}
+	return dec
+}
+
+// iso2022Encoder is a transform.Transformer that converts a utf8 string into
+// the charsets declared in SpecificCharacterSet, inserting escape sequences
+// where the charset changes. The initial charsets are restored before each
+// delimiter (CR, LF, FF, TAB, '\', '^', '=') and at the end of the value.
+// P3.5 6.1.2.5.3.
+type iso2022Encoder struct {
+	// Charsets declared in SpecificCharacterSet, in order.
+	charsets             []*iso2022Charset
+	initialG0, initialG1 *iso2022Charset
+	g0, g1               *iso2022Charset
+	// Encoders for iso2022Charset.enc, created lazily.
+	encoders map[*iso2022Charset]*encoding.Encoder
+}
+
+// errISO2022Unsupported is returned by iso2022Encoder for a character that
+// none of the declared charsets can represent.
+type errISO2022Unsupported rune
+
+func (e errISO2022Unsupported) Error() string {
+	return fmt.Sprintf("character %q is not in the declared character sets", rune(e))
+}
+
+func (e *iso2022Encoder) Reset() {
+	e.g0, e.g1 = e.initialG0, e.initialG1
+}
+
+// escapes returns the escape sequences that designate g0 and g1, for the ones
+// that differ from the current state.
+func (e *iso2022Encoder) escapes(g0, g1 *iso2022Charset) []byte {
+	var seq []byte
+	if g0 != e.g0 {
+		seq = append(append(seq, iso2022ESC), g0.escape...)
+	}
+	if g1 != e.g1 && g1 != nil {
+		seq = append(append(seq, iso2022ESC), g1.escape...)
+	}
+	return seq
+}
+
+func (e *iso2022Encoder) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		if !atEOF && !utf8.FullRune(src[nSrc:]) {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+		r, size := utf8.DecodeRune(src[nSrc:])
+		g0, g1 := e.g0, e.g1
+		var out []byte
+		if r < utf8.RuneSelf {
+			if strings.ContainsRune("\r\n\f\t\\^=", r) {
+				g0, g1 = e.initialG0, e.initialG1
+			} else if g0.width > 1 {
+				g0 = e.initialG0
+			}
+			out = []byte{byte(r)}
+		} else {
+			var cs *iso2022Charset
+			cs, out = e.encodeRune(r)
+			if cs == nil {
+				return nDst, nSrc, errISO2022Unsupported(r)
+			}
+			if cs.g0 {
+				g0 = cs
+			} else {
+				g1 = cs
+			}
+		}
+		out = append(e.escapes(g0, g1), out...)
+		if nDst+len(out) > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], out)
+		nSrc += size
+		e.g0, e.g1 = g0, g1
+	}
+	if atEOF {
+		seq := e.escapes(e.initialG0, e.initialG1)
+		if nDst+len(seq) > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], seq)
+		e.Reset()
+	}
+	return nDst, nSrc, nil
+}
+
+// encodeRune finds the charset that can represent "r", preferring the ones
+// currently designated, and returns the bytes that represent "r" in it.
+func (e *iso2022Encoder) encodeRune(r rune) (*iso2022Charset, []byte) {
+	candidates := append([]*iso2022Charset{e.g1, e.g0}, e.charsets...)
+	for _, cs := range candidates {
+		if cs == nil || cs.enc == nil {
+			continue
+		}
+		if b := e.encodeRuneIn(cs, r); b != nil {
+			return cs, b
+		}
+	}
+	return nil, nil
+}
+
+func (e *iso2022Encoder) encodeRuneIn(cs *iso2022Charset, r rune) []byte {
+	if e.encoders == nil {
+		e.encoders = map[*iso2022Charset]*encoding.Encoder{}
+	}
+	enc, ok := e.encoders[cs]
+	if !ok {
+		enc = cs.enc.NewEncoder()
+		e.encoders[cs] = enc
+	}
+	var in [utf8.UTFMax]byte
+	var euc [8]byte
+	enc.Reset()
+	n, _, err := enc.Transform(euc[:], in[:utf8.EncodeRune(in[:], r)], true)
+	if err != nil {
+		return nil
+	}
+	// Check that the EUC form belongs to "cs", e.g., JIS X 0208 and not
+	// JIS X 0212 for EUC-JP, and strip the prefix.
+	b := euc[:n]
+	if cs.prefix != 0 {
+		if len(b) == 0 || b[0] != cs.prefix {
+			return nil
+		}
+		b = b[1:]
+	}
+	if len(b) != cs.width {
+		return nil
+	}
+	minByte := byte(0xa1)
+	if cs.width == 1 {
+		// 96-character sets, such as ISO 8859, use 0xa0 too.
+		minByte = 0xa0
+	}
+	out := make([]byte, len(b))
+	for i, c := range b {
+		if c < minByte {
+			return nil
+		}
+		out[i] = c
+		if cs.g0 {
+			out[i] &^= 0x80
+		}
+	}
+	return out
+}
+
+// splitISO2022 splits "b" at each byte found in "delims". A delimiter byte is
+// ignored while a multi-byte charset is designated to G0, since it is then
+// part of a character. P3.5 6.1.2.5.3. The delimiters are returned in the
+// second result, so len(pieces)==len(delims)+1.
+func splitISO2022(b []byte, delims string) (pieces [][]byte, found []byte) {
+	multiByte := false
+	start := 0
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		if c == iso2022ESC {
+			if cs, n := findISO2022Escape(b[i:]); cs != nil {
+				if cs.g0 {
+					multiByte = cs.width > 1
+				}
+				i += n - 1
+			}
+			continue
+		}
+		if !multiByte && strings.IndexByte(delims, c) >= 0 {
+			pieces = append(pieces, b[start:i])
+			found = append(found, c)
+			start = i + 1
+		}
+	}
+	return append(pieces, b[start:]), found
+}
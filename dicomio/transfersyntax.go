@@ -58,6 +58,8 @@ func ParseTransferSyntaxUID(uid string) (bo binary.ByteOrder, implicit IsImplici
 	case dicomuid.ImplicitVRLittleEndian:
 		return binary.LittleEndian, ImplicitVR, nil
 	case dicomuid.DeflatedExplicitVRLittleEndian:
+		// The dataset is explicit little endian once inflated. The
+		// caller is responsible for calling Decoder.StartInflating.
 		fallthrough
 	case dicomuid.ExplicitVRLittleEndian:
 		return binary.LittleEndian, ExplicitVR, nil
@@ -33,6 +33,10 @@ def list_tags() -> List[Tag]:
             # https://github.com/dgobbi/vtk-dicom/issues/38 for
 	    # some discussions.
             vr = "US"
+        elif vr == "LT" and m.group(3) == "lt":
+            # "lt" is DCMTK's pseudo VR for LUT data, which is "US or
+            # OW" in the standard. It is not a long text.
+            vr = "US"
         elif vr == "OX":
 	    # TODO(saito) I'm less sure about the OX rule. Where is
 	    # this crap defined in the standard??
@@ -270,6 +274,7 @@ DATA = """#
 (0008,1163)	FD	TimeRange	2	DICOM_2011
 (0008,1164)	SQ	FrameExtractionSequence	1	DICOM_2011
 (0008,1167)	UI	MultiFrameSourceSOPInstanceUID	1	DICOM_2011
+(0008,1190)	UR	RetrieveURL	1	DICOM_2019
 (0008,1195)	UI	TransactionUID	1	DICOM_2011
 (0008,1197)	US	FailureReason	1	DICOM_2011
 (0008,1198)	SQ	FailedSOPSequence	1	DICOM_2011
@@ -3347,6 +3352,10 @@ DATA = """#
 (6000-60FF,1303)	DS	ROIStandardDeviation	1	DICOM_2011
 (6000-60FF,1500)	LO	OverlayLabel	1	DICOM_2011
 (6000-60FF,3000)	ox	OverlayData	1	DICOM_2011
+(7FE0,0001)	OV	ExtendedOffsetTable	1	DICOM_2019
+(7FE0,0002)	OV	ExtendedOffsetTableLengths	1	DICOM_2019
+(7FE0,0008)	OF	FloatPixelData	1	DICOM_2019
+(7FE0,0009)	OD	DoubleFloatPixelData	1	DICOM_2019
 (7FE0,0010)	ox	PixelData	1	DICOM_2011
 (FFFA,FFFA)	SQ	DigitalSignaturesSequence	1	DICOM_2011
 (FFFC,FFFC)	OB	DataSetTrailingPadding	1	DICOM_2011
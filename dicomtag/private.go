@@ -0,0 +1,197 @@
+package dicomtag
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PrivateTagInfo stores information about an element in a private block. A
+// private element (gggg,xxee) is identified by the value of the Private Creator
+// element (gggg,00xx) that reserves block xx, its group gggg, and its offset
+// ee within the block. PS3.5 7.8.1.
+type PrivateTagInfo struct {
+	// Value of the Private Creator element, e.g., "SIEMENS CSA HEADER".
+	Creator string
+	// Group of the element. Must be odd.
+	Group uint16
+	// Offset of the element within the private block, in range [0,0xff].
+	Offset uint8
+	// Data encoding "UL", "CS", etc.
+	VR string
+	// Human-readable name of the tag, e.g., "CSAImageHeaderInfo"
+	Name string
+	// Cardinality (# of values expected in the element)
+	VM string
+}
+
+type privateTagKey struct {
+	creator string
+	group   uint16
+	offset  uint8
+}
+
+var (
+	privateDictMu sync.RWMutex
+	privateDict   = map[privateTagKey]PrivateTagInfo{}
+)
+
+func init() {
+	for _, info := range []PrivateTagInfo{
+		{"SIEMENS CSA HEADER", 0x0029, 0x08, "CS", "CSAImageHeaderType", "1"},
+		{"SIEMENS CSA HEADER", 0x0029, 0x09, "LO", "CSAImageHeaderVersion", "1"},
+		{"SIEMENS CSA HEADER", 0x0029, 0x10, "OB", "CSAImageHeaderInfo", "1"},
+		{"SIEMENS CSA HEADER", 0x0029, 0x18, "CS", "CSASeriesHeaderType", "1"},
+		{"SIEMENS CSA HEADER", 0x0029, 0x19, "LO", "CSASeriesHeaderVersion", "1"},
+		{"SIEMENS CSA HEADER", 0x0029, 0x20, "OB", "CSASeriesHeaderInfo", "1"},
+	} {
+		RegisterPrivateTag(info)
+	}
+}
+
+// RegisterPrivateTag adds an entry to the private dictionary. It overwrites an
+// existing entry for the same <creator, group, offset>. Thread safe.
+func RegisterPrivateTag(info PrivateTagInfo) {
+	info.Creator = strings.TrimSpace(info.Creator)
+	privateDictMu.Lock()
+	privateDict[privateTagKey{info.Creator, info.Group, info.Offset}] = info
+	privateDictMu.Unlock()
+}
+
+// FindPrivate finds information about the private data element "tag", given
+// the value of the Private Creator element that reserves its block. If the
+// element is not registered in the private dictionary, it returns an error.
+func FindPrivate(tag Tag, creator string) (TagInfo, error) {
+	if _, ok := PrivateCreatorTag(tag); !ok {
+		return TagInfo{}, fmt.Errorf("%v is not a private data element", tag)
+	}
+	key := privateTagKey{strings.TrimSpace(creator), tag.Group, uint8(tag.Element)}
+	privateDictMu.RLock()
+	info, ok := privateDict[key]
+	privateDictMu.RUnlock()
+	if !ok {
+		return TagInfo{}, fmt.Errorf("Could not find private tag %v of creator '%s' in dictionary", tag, creator)
+	}
+	return TagInfo{Tag: tag, VR: info.VR, Name: info.Name, VM: info.VM}, nil
+}
+
+// PrivateCreators maps the tags of Private Creator elements, (gggg,0010-00FF),
+// to their values. It represents the private blocks reserved in one dataset
+// or sequence item.
+type PrivateCreators map[Tag]string
+
+// Find finds information about "tag". It consults the standard dictionary
+// first, and then the private dictionary using the creator that reserves the
+// block of "tag".
+func (c PrivateCreators) Find(tag Tag) (TagInfo, error) {
+	if info, err := Find(tag); err == nil {
+		return info, nil
+	}
+	creatorTag, ok := PrivateCreatorTag(tag)
+	if !ok {
+		return Find(tag)
+	}
+	creator, ok := c[creatorTag]
+	if !ok {
+		return TagInfo{}, fmt.Errorf("Private Creator %v for tag %v not found", creatorTag, tag)
+	}
+	return FindPrivate(tag, creator)
+}
+
+// LoadPrivateDictionary reads private dictionary entries from "in" and
+// registers them. The format is the one used by DCMTK's private.dic: one
+// entry per line, each consisting of tab- or space-separated fields
+//
+//	(0029,"SIEMENS CSA HEADER",10)	OB	CSAImageHeaderInfo	1	PrivateTag
+//
+// The first field is <group, creator, offset in the block>; the others are the
+// VR, the name and the VM. Extra fields are ignored. Empty lines and lines
+// starting with '#' are skipped.
+func LoadPrivateDictionary(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		info, err := parsePrivateDictionaryLine(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", lineno, err)
+		}
+		RegisterPrivateTag(info)
+	}
+	return scanner.Err()
+}
+
+// LoadPrivateDictionaryFromFile is similar to LoadPrivateDictionary, but it
+// reads the given file.
+func LoadPrivateDictionaryFromFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close() // nolint: errcheck
+	return LoadPrivateDictionary(in)
+}
+
+func parsePrivateDictionaryLine(line string) (PrivateTagInfo, error) {
+	// The creator string may contain spaces and parentheses, so locate the
+	// end of the key by the closing quote instead of splitting the line.
+	lq, rq := strings.Index(line, "\""), strings.LastIndex(line, "\"")
+	if !strings.HasPrefix(line, "(") || lq < 0 || lq == rq {
+		return PrivateTagInfo{}, fmt.Errorf("private creator not found in '%s'", line)
+	}
+	end := strings.Index(line[rq:], ")")
+	if end < 0 {
+		return PrivateTagInfo{}, fmt.Errorf("malformed tag in '%s'", line)
+	}
+	end += rq
+	group, err := strconv.ParseUint(strings.TrimSpace(strings.TrimSuffix(line[1:lq], ",")), 16, 16)
+	if err != nil {
+		return PrivateTagInfo{}, fmt.Errorf("malformed group in '%s': %v", line, err)
+	}
+	if !IsPrivate(uint16(group)) {
+		return PrivateTagInfo{}, fmt.Errorf("group %04x in '%s' is not private", group, line)
+	}
+	offset, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line[rq+1:end], ",")), 16, 16)
+	if err != nil {
+		return PrivateTagInfo{}, fmt.Errorf("malformed element in '%s': %v", line, err)
+	}
+	fields := strings.Fields(line[end+1:])
+	if len(fields) < 2 {
+		return PrivateTagInfo{}, fmt.Errorf("VR or name not found in '%s'", line)
+	}
+	info := PrivateTagInfo{
+		Creator: line[lq+1 : rq],
+		Group:   uint16(group),
+		// Accept both "10" and "1010"; only the low byte is
+		// meaningful.
+		Offset: uint8(offset),
+		VR:     fields[0],
+		Name:   fields[1],
+		VM:     "1",
+	}
+	if len(fields) >= 3 {
+		info.VM = fields[2]
+	}
+	return info, nil
+}
+
+// DebugStringWithCreator is similar to DebugString, but for a private data
+// element whose Private Creator is "creator", it returns a string of form
+// "(gggg,eeee)[creator:name]" if the element is found in the private
+// dictionary.
+func DebugStringWithCreator(tag Tag, creator string) string {
+	if creator != "" {
+		if e, err := FindPrivate(tag, creator); err == nil {
+			return fmt.Sprintf("(%04x,%04x)[%s:%s]", tag.Group, tag.Element, strings.TrimSpace(creator), e.Name)
+		}
+	}
+	return DebugString(tag)
+}
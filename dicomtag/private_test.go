@@ -0,0 +1,84 @@
+package dicomtag
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindPrivate(t *testing.T) {
+	info, err := FindPrivate(Tag{0x0029, 0x1010}, "SIEMENS CSA HEADER ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "CSAImageHeaderInfo" || info.VR != "OB" || info.Tag != (Tag{0x0029, 0x1010}) {
+		t.Errorf("Wrong private tag: %+v", info)
+	}
+	// The same offset in another block.
+	if _, err := FindPrivate(Tag{0x0029, 0x1110}, "SIEMENS CSA HEADER"); err != nil {
+		t.Error(err)
+	}
+	if _, err := FindPrivate(Tag{0x0029, 0x1010}, "ACME"); err == nil {
+		t.Error("Unknown creator must not be found")
+	}
+	if _, err := FindPrivate(Tag{0x0029, 0x0010}, "SIEMENS CSA HEADER"); err == nil {
+		t.Error("Private creator is not a private data element")
+	}
+}
+
+func TestPrivateCreatorsFind(t *testing.T) {
+	creators := PrivateCreators{Tag{0x0029, 0x0011}: "SIEMENS CSA HEADER"}
+	info, err := creators.Find(Tag{0x0029, 0x1120})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "CSASeriesHeaderInfo" {
+		t.Errorf("Wrong private tag: %+v", info)
+	}
+	if _, err := creators.Find(Tag{0x0029, 0x1020}); err == nil {
+		t.Error("Block 0x10 is not reserved")
+	}
+	if info, err := creators.Find(PatientName); err != nil || info.Name != "PatientName" {
+		t.Errorf("Public tag: %+v %v", info, err)
+	}
+	if s := DebugStringWithCreator(Tag{0x0029, 0x1120}, "SIEMENS CSA HEADER"); s != "(0029,1120)[SIEMENS CSA HEADER:CSASeriesHeaderInfo]" {
+		t.Errorf("Wrong debug string: %s", s)
+	}
+	if s := DebugStringWithCreator(Tag{0x0029, 0x1120}, ""); s != "(0029,1120)[private]" {
+		t.Errorf("Wrong debug string: %s", s)
+	}
+}
+
+func TestLoadPrivateDictionary(t *testing.T) {
+	dict := `# Comment
+(0019,"GEMS_ACQU_01",02)	SL	GEDetectorsPerRotation	1	PrivateTag
+(0043,"ACME (V2)",1010)	DS	AcmeCalibration	3	PrivateTag
+`
+	if err := LoadPrivateDictionary(strings.NewReader(dict)); err != nil {
+		t.Fatal(err)
+	}
+	info, err := FindPrivate(Tag{0x0019, 0x1002}, "GEMS_ACQU_01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.VR != "SL" || info.Name != "GEDetectorsPerRotation" || info.VM != "1" {
+		t.Errorf("Wrong private tag: %+v", info)
+	}
+	info, err = FindPrivate(Tag{0x0043, 0x1210}, "ACME (V2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.VR != "DS" || info.VM != "3" {
+		t.Errorf("Wrong private tag: %+v", info)
+	}
+
+	for _, bad := range []string{
+		`(0018,"ACME",10)	DS	Foo	1`,
+		`(0019,ACME,10)	DS	Foo	1`,
+		`(0019,"ACME",zz)	DS	Foo	1`,
+		`(0019,"ACME",10)	DS`,
+	} {
+		if err := LoadPrivateDictionary(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expect error for '%s'", bad)
+		}
+	}
+}
@@ -45,6 +45,23 @@ func IsPrivate(group uint16) bool {
 	return group%2 == 1
 }
 
+// IsPrivateCreator checks if the tag is a Private Creator element, i.e.,
+// (gggg,0010-00FF) with an odd gggg. PS3.5 7.8.1.
+func IsPrivateCreator(tag Tag) bool {
+	return IsPrivate(tag.Group) && tag.Element >= 0x0010 && tag.Element <= 0x00FF
+}
+
+// PrivateCreatorTag returns the tag of the Private Creator element that
+// reserves the block of the given private data element. E.g., it returns
+// (0029,0010) for (0029,1008). It returns false if the tag is not a private
+// data element. PS3.5 7.8.1.
+func PrivateCreatorTag(tag Tag) (Tag, bool) {
+	if !IsPrivate(tag.Group) || tag.Element < 0x1000 {
+		return Tag{}, false
+	}
+	return Tag{Group: tag.Group, Element: tag.Element >> 8}, true
+}
+
 // String returns a string of form "(0008,1234)", where 0x0008 is t.Group,
 // 0x1234 is t.Element.
 func (t Tag) String() string {
@@ -99,6 +116,10 @@ const (
 	VRDate
 	// VRPixelData means the element stores a PixelDataInfo
 	VRPixelData
+	// VRUInt64List means the element stores a list of uint64s
+	VRUInt64List
+	// VRInt64List means the element stores a list of int64s
+	VRInt64List
 )
 
 // GetVRKind returns the golang value encoding of an element with <tag, vr>.
@@ -113,11 +134,11 @@ func GetVRKind(tag Tag, vr string) VRKind {
 		return VRDate
 	case "AT":
 		return VRTagList
-	case "OW", "OB":
+	case "OW", "OB", "UN":
 		return VRBytes
-	case "LT", "UT":
+	case "LT", "UT", "UR":
 		return VRString
-	case "UL":
+	case "UL", "OL":
 		return VRUInt32List
 	case "SL":
 		return VRInt32List
@@ -125,10 +146,14 @@ func GetVRKind(tag Tag, vr string) VRKind {
 		return VRUInt16List
 	case "SS":
 		return VRInt16List
-	case "FL":
+	case "FL", "OF":
 		return VRFloat32List
-	case "FD":
+	case "FD", "OD":
 		return VRFloat64List
+	case "UV", "OV":
+		return VRUInt64List
+	case "SV":
+		return VRInt64List
 	case "SQ":
 		return VRSequence
 	default:
@@ -190,15 +215,33 @@ func DebugString(tag Tag) string {
 	return fmt.Sprintf("(%04x,%04x)[%s]", tag.Group, tag.Element, e.Name)
 }
 
+// ParseTag parses a tag of form "(gggg,eeee)" or "gggg,eeee", where gggg and
+// eeee are hex numbers, or the name of a tag in the dictionary, such as
+// "PatientName".
+func ParseTag(s string) (Tag, error) {
+	s = strings.TrimSpace(s)
+	if strings.ContainsRune(s, ',') {
+		return parseTag(s)
+	}
+	ti, err := FindByName(s)
+	if err != nil {
+		return Tag{}, err
+	}
+	return ti.Tag, nil
+}
+
 // Split a tag into a group and element, represented as a hex value
 // TODO: support group ranges (6000-60FF,0803)
 func parseTag(tag string) (Tag, error) {
 	parts := strings.Split(strings.Trim(tag, "()"), ",")
-	group, err := strconv.ParseInt(parts[0], 16, 0)
+	if len(parts) != 2 {
+		return Tag{}, fmt.Errorf("%s: Failed to parse as tag", tag)
+	}
+	group, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 16, 16)
 	if err != nil {
 		return Tag{}, err
 	}
-	elem, err := strconv.ParseInt(parts[1], 16, 0)
+	elem, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 16, 16)
 	if err != nil {
 		return Tag{}, err
 	}
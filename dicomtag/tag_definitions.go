@@ -144,6 +144,7 @@ var CalculatedFrameList = Tag{0x0008, 0x1162}
 var TimeRange = Tag{0x0008, 0x1163}
 var FrameExtractionSequence = Tag{0x0008, 0x1164}
 var MultiFrameSourceSOPInstanceUID = Tag{0x0008, 0x1167}
+var RetrieveURL = Tag{0x0008, 0x1190}
 var TransactionUID = Tag{0x0008, 0x1195}
 var FailureReason = Tag{0x0008, 0x1197}
 var FailedSOPSequence = Tag{0x0008, 0x1198}
@@ -3199,6 +3200,10 @@ var WaveformPaddingValue = Tag{0x5400, 0x100A}
 var WaveformData = Tag{0x5400, 0x1010}
 var FirstOrderPhaseCorrectionAngle = Tag{0x5600, 0x0010}
 var SpectroscopyData = Tag{0x5600, 0x0020}
+var ExtendedOffsetTable = Tag{0x7FE0, 0x0001}
+var ExtendedOffsetTableLengths = Tag{0x7FE0, 0x0002}
+var FloatPixelData = Tag{0x7FE0, 0x0008}
+var DoubleFloatPixelData = Tag{0x7FE0, 0x0009}
 var PixelData = Tag{0x7FE0, 0x0010}
 var DigitalSignaturesSequence = Tag{0xFFFA, 0xFFFA}
 var DataSetTrailingPadding = Tag{0xFFFC, 0xFFFC}
@@ -3509,6 +3514,7 @@ func maybeInitTagDict() {
 	tagDict[Tag{0x0008, 0x1163}] = TagInfo{Tag{0x0008, 0x1163}, "FD", "TimeRange", "2"}
 	tagDict[Tag{0x0008, 0x1164}] = TagInfo{Tag{0x0008, 0x1164}, "SQ", "FrameExtractionSequence", "1"}
 	tagDict[Tag{0x0008, 0x1167}] = TagInfo{Tag{0x0008, 0x1167}, "UI", "MultiFrameSourceSOPInstanceUID", "1"}
+	tagDict[Tag{0x0008, 0x1190}] = TagInfo{Tag{0x0008, 0x1190}, "UR", "RetrieveURL", "1"}
 	tagDict[Tag{0x0008, 0x1195}] = TagInfo{Tag{0x0008, 0x1195}, "UI", "TransactionUID", "1"}
 	tagDict[Tag{0x0008, 0x1197}] = TagInfo{Tag{0x0008, 0x1197}, "US", "FailureReason", "1"}
 	tagDict[Tag{0x0008, 0x1198}] = TagInfo{Tag{0x0008, 0x1198}, "SQ", "FailedSOPSequence", "1"}
@@ -4808,7 +4814,7 @@ func maybeInitTagDict() {
 	tagDict[Tag{0x0028, 0x3002}] = TagInfo{Tag{0x0028, 0x3002}, "US", "LUTDescriptor", "3"}
 	tagDict[Tag{0x0028, 0x3003}] = TagInfo{Tag{0x0028, 0x3003}, "LO", "LUTExplanation", "1"}
 	tagDict[Tag{0x0028, 0x3004}] = TagInfo{Tag{0x0028, 0x3004}, "LO", "ModalityLUTType", "1"}
-	tagDict[Tag{0x0028, 0x3006}] = TagInfo{Tag{0x0028, 0x3006}, "LT", "LUTData", "1-n"}
+	tagDict[Tag{0x0028, 0x3006}] = TagInfo{Tag{0x0028, 0x3006}, "US", "LUTData", "1-n"}
 	tagDict[Tag{0x0028, 0x3010}] = TagInfo{Tag{0x0028, 0x3010}, "SQ", "VOILUTSequence", "1"}
 	tagDict[Tag{0x0028, 0x3110}] = TagInfo{Tag{0x0028, 0x3110}, "SQ", "SoftcopyVOILUTSequence", "1"}
 	tagDict[Tag{0x0028, 0x6010}] = TagInfo{Tag{0x0028, 0x6010}, "US", "RepresentativeFrameNumber", "1"}
@@ -6564,6 +6570,10 @@ func maybeInitTagDict() {
 	tagDict[Tag{0x5400, 0x1010}] = TagInfo{Tag{0x5400, 0x1010}, "OW", "WaveformData", "1"}
 	tagDict[Tag{0x5600, 0x0010}] = TagInfo{Tag{0x5600, 0x0010}, "OF", "FirstOrderPhaseCorrectionAngle", "1"}
 	tagDict[Tag{0x5600, 0x0020}] = TagInfo{Tag{0x5600, 0x0020}, "OF", "SpectroscopyData", "1"}
+	tagDict[Tag{0x7FE0, 0x0001}] = TagInfo{Tag{0x7FE0, 0x0001}, "OV", "ExtendedOffsetTable", "1"}
+	tagDict[Tag{0x7FE0, 0x0002}] = TagInfo{Tag{0x7FE0, 0x0002}, "OV", "ExtendedOffsetTableLengths", "1"}
+	tagDict[Tag{0x7FE0, 0x0008}] = TagInfo{Tag{0x7FE0, 0x0008}, "OF", "FloatPixelData", "1"}
+	tagDict[Tag{0x7FE0, 0x0009}] = TagInfo{Tag{0x7FE0, 0x0009}, "OD", "DoubleFloatPixelData", "1"}
 	tagDict[Tag{0x7FE0, 0x0010}] = TagInfo{Tag{0x7FE0, 0x0010}, "OW", "PixelData", "1"}
 	tagDict[Tag{0xFFFA, 0xFFFA}] = TagInfo{Tag{0xFFFA, 0xFFFA}, "SQ", "DigitalSignaturesSequence", "1"}
 	tagDict[Tag{0xFFFC, 0xFFFC}] = TagInfo{Tag{0xFFFC, 0xFFFC}, "OB", "DataSetTrailingPadding", "1"}
@@ -6895,7 +6905,7 @@ func maybeInitTagDict() {
 	tagDict[Tag{0x0028, 0x1111}] = TagInfo{Tag{0x0028, 0x1111}, "US", "RETIRED_LargeRedPaletteColorLookupTableDescriptor", "4"}
 	tagDict[Tag{0x0028, 0x1112}] = TagInfo{Tag{0x0028, 0x1112}, "US", "RETIRED_LargeGreenPaletteColorLookupTableDescriptor", "4"}
 	tagDict[Tag{0x0028, 0x1113}] = TagInfo{Tag{0x0028, 0x1113}, "US", "RETIRED_LargeBluePaletteColorLookupTableDescriptor", "4"}
-	tagDict[Tag{0x0028, 0x1200}] = TagInfo{Tag{0x0028, 0x1200}, "LT", "RETIRED_GrayLookupTableData", "1-n"}
+	tagDict[Tag{0x0028, 0x1200}] = TagInfo{Tag{0x0028, 0x1200}, "US", "RETIRED_GrayLookupTableData", "1-n"}
 	tagDict[Tag{0x0028, 0x1211}] = TagInfo{Tag{0x0028, 0x1211}, "OW", "RETIRED_LargeRedPaletteColorLookupTableData", "1"}
 	tagDict[Tag{0x0028, 0x1212}] = TagInfo{Tag{0x0028, 0x1212}, "OW", "RETIRED_LargeGreenPaletteColorLookupTableData", "1"}
 	tagDict[Tag{0x0028, 0x1213}] = TagInfo{Tag{0x0028, 0x1213}, "OW", "RETIRED_LargeBluePaletteColorLookupTableData", "1"}
@@ -45,6 +45,22 @@ func TestSplitTag(t *testing.T) {
 
 }
 
+func TestParseTag(t *testing.T) {
+	for _, s := range []string{"0010,0020", "(0010,0020)", " 0010, 0020 ", "PatientID"} {
+		tag, err := ParseTag(s)
+		if err != nil {
+			t.Errorf("ParseTag(%q): %v", s, err)
+		} else if tag != PatientID {
+			t.Errorf("ParseTag(%q): got %v", s, tag)
+		}
+	}
+	for _, s := range []string{"", "0010", "0010,0020,0030", "10000,0020", "0010,XXXX", "NoSuchTag"} {
+		if _, err := ParseTag(s); err == nil {
+			t.Errorf("ParseTag(%q): expected an error", s)
+		}
+	}
+}
+
 func BenchmarkFindMetaGroupLengthTag(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		if _, err := Find(Tag{2, 0}); err != nil {
@@ -62,3 +78,34 @@ func BenchmarkFindPixelDataTag(b *testing.B) {
 
 	}
 }
+
+func TestPrivateCreatorTag(t *testing.T) {
+	if !IsPrivateCreator(Tag{0x0029, 0x0010}) || IsPrivateCreator(Tag{0x0029, 0x1010}) || IsPrivateCreator(Tag{0x0028, 0x0010}) {
+		t.Error("IsPrivateCreator")
+	}
+	creator, ok := PrivateCreatorTag(Tag{0x0029, 0x1008})
+	if !ok || creator != (Tag{0x0029, 0x0010}) {
+		t.Errorf("Wrong creator: %v %v", creator, ok)
+	}
+	if _, ok := PrivateCreatorTag(Tag{0x0029, 0x0010}); ok {
+		t.Error("Private creator has no creator")
+	}
+	if _, ok := PrivateCreatorTag(Tag{0x0010, 0x1010}); ok {
+		t.Error("Public tag has no creator")
+	}
+}
+
+func TestGetVRKind(t *testing.T) {
+	for vr, kind := range map[string]VRKind{
+		"OB": VRBytes, "UN": VRBytes, "UR": VRString, "OL": VRUInt32List,
+		"OF": VRFloat32List, "OD": VRFloat64List, "OV": VRUInt64List,
+		"UV": VRUInt64List, "SV": VRInt64List, "UC": VRStringList,
+	} {
+		if k := GetVRKind(Tag{0x0072, 0x0080}, vr); k != kind {
+			t.Errorf("GetVRKind(%s): expect %v, but found %v", vr, kind, k)
+		}
+	}
+	if elem := MustFind(LUTData); elem.VR != "US" {
+		t.Errorf("Wrong VR for LUTData: %s", elem.VR)
+	}
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
 
 	"github.com/msz-kp/go-dicom"
 	"github.com/msz-kp/go-dicom/dicomtag"
@@ -20,6 +22,9 @@ func main() {
 	if len(flag.Args()) == 0 {
 		log.Panic("dicomutil <dicomfile>")
 	}
+	if flag.Arg(0) == "diff" {
+		os.Exit(diff(flag.Args()[1:]))
+	}
 	path := flag.Arg(0)
 	data, err := dicom.ReadDataSetFromFile(path, dicom.ReadOptions{DropPixelData: !*extractImages})
 	if data == nil {
@@ -46,3 +51,64 @@ func main() {
 		}
 	}
 }
+
+// diff implements "dicomutil diff [flags] a.dcm b.dcm". It returns the exit
+// status: 0 if the files are the same, 1 if they differ, 2 on error, as
+// diff(1) does.
+func diff(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := flags.String("format", "text", "Output format, 'text' or 'json'")
+	ignorePixelData := flags.Bool("ignore-pixel-data", false, "Ignore the PixelData element")
+	ignoreMeta := flags.Bool("ignore-meta", false, "Ignore the meta elements, i.e., group 0002")
+	ignorePrivate := flags.Bool("ignore-private", false, "Ignore private elements")
+	exact := flags.Bool("exact", false, "Report differences in padding and number formatting too")
+	flags.Parse(args)
+	if flags.NArg() != 2 || (*format != "text" && *format != "json") {
+		log.Print("dicomutil diff [-format text|json] [-ignore-pixel-data] [-ignore-meta] [-ignore-private] [-exact] <dicomfile> <dicomfile>")
+		return 2
+	}
+	var opts []dicom.DiffOption
+	if *ignorePixelData {
+		opts = append(opts, dicom.IgnorePixelData())
+	}
+	if *ignoreMeta {
+		opts = append(opts, dicom.IgnoreMetaElements())
+	}
+	if *ignorePrivate {
+		opts = append(opts, dicom.IgnorePrivateTags())
+	}
+	if *exact {
+		opts = append(opts, dicom.ExactMatch())
+	}
+	var ds [2]*dicom.DataSet
+	for i, path := range flags.Args() {
+		// DropPixelData would also drop the elements that follow
+		// PixelData, so the whole file is read, and Diff skips PixelData.
+		data, err := dicom.ReadDataSetFromFile(path, dicom.ReadOptions{})
+		if err != nil {
+			log.Printf("Error reading %s: %v", path, err)
+			return 2
+		}
+		ds[i] = data
+	}
+	diffs := dicom.Diff(ds[0], ds[1], opts...)
+	if *format == "json" {
+		if diffs == nil {
+			diffs = []dicom.Difference{}
+		}
+		out, err := json.MarshalIndent(diffs, "", "  ")
+		if err != nil {
+			log.Printf("Error encoding the differences: %v", err)
+			return 2
+		}
+		fmt.Printf("%s\n", out)
+	} else {
+		for _, d := range diffs {
+			fmt.Println(d.String())
+		}
+	}
+	if len(diffs) > 0 {
+		return 1
+	}
+	return 0
+}
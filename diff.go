@@ -0,0 +1,235 @@
+package dicom
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/msz-kp/go-dicom/dicomtag"
+)
+
+// DiffKind is the kind of a Difference.
+type DiffKind int
+
+const (
+	// DiffAdded means the element exists only in the second dataset.
+	DiffAdded DiffKind = iota
+	// DiffRemoved means the element exists only in the first dataset.
+	DiffRemoved
+	// DiffChanged means the element exists in both datasets with different
+	// values.
+	DiffChanged
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffChanged:
+		return "changed"
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(k))
+}
+
+// Difference is one difference found by Diff.
+type Difference struct {
+	Kind DiffKind
+	// Path locates the element in the datasets. For an item that exists in
+	// only one of the datasets, the last step has the item index, and A or B
+	// is the item.
+	Path Path
+	// The element in the first and second dataset. A is nil if
+	// Kind==DiffAdded, and B is nil if Kind==DiffRemoved.
+	A, B *Element
+}
+
+// String returns a one-line description of the difference, e.g.,
+//
+//	changed PatientName: ["Doe^John"] -> ["Doe^Jane"]
+//
+// The format is meant for humans and diff(1); it's not parseable.
+func (d Difference) String() string {
+	switch d.Kind {
+	case DiffAdded:
+		return fmt.Sprintf("%v %s: %s", d.Kind, pathName(d.Path), diffValueString(d.B))
+	case DiffRemoved:
+		return fmt.Sprintf("%v %s: %s", d.Kind, pathName(d.Path), diffValueString(d.A))
+	}
+	return fmt.Sprintf("%v %s: %s -> %s", d.Kind, pathName(d.Path), diffValueString(d.A), diffValueString(d.B))
+}
+
+// MarshalJSON encodes the difference as a JSON object with fields "kind",
+// "path" (in Path.String form), "name" (with element names, as in String), and
+// "a" and "b" (the values, each formatted as in String).
+func (d Difference) MarshalJSON() ([]byte, error) {
+	v := struct {
+		Kind string   `json:"kind"`
+		Path string   `json:"path"`
+		Name string   `json:"name"`
+		A    []string `json:"a,omitempty"`
+		B    []string `json:"b,omitempty"`
+	}{Kind: d.Kind.String(), Path: d.Path.String(), Name: pathName(d.Path)}
+	if d.A != nil {
+		v.A = diffValues(d.A)
+	}
+	if d.B != nil {
+		v.B = diffValues(d.B)
+	}
+	return json.Marshal(v)
+}
+
+// pathName is similar to Path.String, but it uses the element names found in
+// the dictionary.
+func pathName(p Path) string {
+	parts := make([]string, len(p))
+	for i, step := range p {
+		parts[i] = step.String()
+		if ti, err := dicomtag.Find(step.Tag); err == nil {
+			// Keep the item index, if any.
+			parts[i] = ti.Name + parts[i][len("(gggg,eeee)"):]
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// diffValueString formats the values of the element for Difference.String.
+func diffValueString(e *Element) string {
+	return "[" + strings.Join(diffValues(e), ", ") + "]"
+}
+
+// diffValues formats each value of the element. Binary values and sequences
+// are summarized.
+func diffValues(e *Element) []string {
+	if e.Tag == dicomtag.Item {
+		return []string{fmt.Sprintf("item{%d elements}", len(e.Value))}
+	}
+	if e.VR == "SQ" {
+		return []string{fmt.Sprintf("sequence{%d items}", len(e.Value))}
+	}
+	values := make([]string, len(e.Value))
+	for i, v := range e.Value {
+		switch v := v.(type) {
+		case string:
+			values[i] = fmt.Sprintf("%q", v)
+		case []byte:
+			values[i] = fmt.Sprintf("bytes{%d}", len(v))
+		case PixelDataInfo:
+			values[i] = fmt.Sprintf("frames{%d}", len(v.Frames))
+		default:
+			values[i] = fmt.Sprint(v)
+		}
+	}
+	return values
+}
+
+// DiffOption is an option passed to Diff.
+type DiffOption func(*diffOptSet)
+
+type diffOptSet struct {
+	ignorePixelData    bool
+	ignoreMetaElements bool
+	ignorePrivateTags  bool
+	exact              bool
+}
+
+// IgnorePixelData makes Diff skip the PixelData element.
+func IgnorePixelData() DiffOption {
+	return func(o *diffOptSet) { o.ignorePixelData = true }
+}
+
+// IgnoreMetaElements makes Diff skip the meta elements, i.e., those with
+// Tag.Group==2.
+func IgnoreMetaElements() DiffOption {
+	return func(o *diffOptSet) { o.ignoreMetaElements = true }
+}
+
+// IgnorePrivateTags makes Diff skip private elements, including the private
+// creators.
+func IgnorePrivateTags() DiffOption {
+	return func(o *diffOptSet) { o.ignorePrivateTags = true }
+}
+
+// ExactMatch makes Diff compare elements by Element.Equal. By default, they
+// are compared by Element.EqualFold, so differences in padding and the like
+// are not reported.
+func ExactMatch() DiffOption {
+	return func(o *diffOptSet) { o.exact = true }
+}
+
+func (o *diffOptSet) ignored(tag dicomtag.Tag) bool {
+	return (o.ignorePixelData && tag == dicomtag.PixelData) ||
+		(o.ignoreMetaElements && tag.Group == dicomtag.MetadataGroup) ||
+		(o.ignorePrivateTags && dicomtag.IsPrivate(tag.Group))
+}
+
+// byTag maps the tags of "elems" to their first occurrence, skipping the
+// ignored ones.
+func (o *diffOptSet) byTag(elems []*Element) map[dicomtag.Tag]*Element {
+	m := make(map[dicomtag.Tag]*Element, len(elems))
+	for _, elem := range elems {
+		if _, ok := m[elem.Tag]; !ok && !o.ignored(elem.Tag) {
+			m[elem.Tag] = elem
+		}
+	}
+	return m
+}
+
+// Diff compares two datasets and returns the differences, sorted by tag. It
+// descends into sequences that exist in both datasets, and compares their
+// items one by one. A dataset that contains the same tag more than once is
+// compared by the first occurrence.
+func Diff(a, b *DataSet, opts ...DiffOption) []Difference {
+	o := diffOptSet{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var diffs []Difference
+	diffElements(a.Elements, b.Elements, nil, &o, &diffs)
+	return diffs
+}
+
+func diffElements(a, b []*Element, path Path, o *diffOptSet, diffs *[]Difference) {
+	byTagA, byTagB := o.byTag(a), o.byTag(b)
+	var tags []dicomtag.Tag
+	for tag := range byTagA {
+		tags = append(tags, tag)
+	}
+	for tag := range byTagB {
+		if _, ok := byTagA[tag]; !ok {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Compare(tags[j]) < 0 })
+	equal := (*Element).EqualFold
+	if o.exact {
+		equal = (*Element).Equal
+	}
+	for _, tag := range tags {
+		elemA, elemB := byTagA[tag], byTagB[tag]
+		elemPath := appendPathStep(path, PathStep{Tag: tag, Index: NoItem})
+		switch {
+		case elemB == nil:
+			*diffs = append(*diffs, Difference{Kind: DiffRemoved, Path: elemPath, A: elemA})
+		case elemA == nil:
+			*diffs = append(*diffs, Difference{Kind: DiffAdded, Path: elemPath, B: elemB})
+		case elemA.VR == "SQ" && elemB.VR == "SQ":
+			itemsA, itemsB := elemA.GetElements(), elemB.GetElements()
+			for i := 0; i < len(itemsA) || i < len(itemsB); i++ {
+				itemPath := appendPathStep(path, PathStep{Tag: tag, Index: i})
+				switch {
+				case i >= len(itemsB):
+					*diffs = append(*diffs, Difference{Kind: DiffRemoved, Path: itemPath, A: itemsA[i]})
+				case i >= len(itemsA):
+					*diffs = append(*diffs, Difference{Kind: DiffAdded, Path: itemPath, B: itemsB[i]})
+				default:
+					diffElements(itemsA[i].GetElements(), itemsB[i].GetElements(), itemPath, o, diffs)
+				}
+			}
+		case !equal(elemA, elemB):
+			*diffs = append(*diffs, Difference{Kind: DiffChanged, Path: elemPath, A: elemA, B: elemB})
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package dicom_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/msz-kp/go-dicom"
+	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func diffStrings(diffs []dicom.Difference) []string {
+	var s []string
+	for _, d := range diffs {
+		s = append(s, d.String())
+	}
+	return s
+}
+
+func TestDiff(t *testing.T) {
+	a := &dicom.DataSet{Elements: []*dicom.Element{
+		dicom.MustNewElement(dicomtag.TransferSyntaxUID, "1.2.840.10008.1.2"),
+		dicom.MustNewElement(dicomtag.PatientName, "Doe^John "),
+		dicom.MustNewElement(dicomtag.PatientID, "1234"),
+		dicom.MustNewElement(dicomtag.ReferencedSeriesSequence,
+			newReferencedSeries("1.2.1", "1.2.1.1", "1.2.1.2"),
+			newReferencedSeries("1.2.2")),
+		{Tag: dicomtag.Tag{Group: 0x0009, Element: 0x1001}, VR: "LO", Value: []interface{}{"a"}},
+		dicom.MustNewElement(dicomtag.PixelData, dicom.PixelDataInfo{Frames: [][]byte{{1, 2}}}),
+	}}
+	b := a.Clone()
+	require.Empty(t, dicom.Diff(a, b))
+
+	b.Set(dicom.MustNewElement(dicomtag.TransferSyntaxUID, "1.2.840.10008.1.2.1"))
+	b.Set(dicom.MustNewElement(dicomtag.PatientName, "Doe^John"))
+	b.Remove(dicomtag.PatientID)
+	b.Set(dicom.MustNewElement(dicomtag.StudyID, "1"))
+	require.NoError(t, b.SetElementByPath("ReferencedSeriesSequence[0].ReferencedInstanceSequence[1].ReferencedSOPInstanceUID", "1.2.1.3"))
+	require.NoError(t, b.SetElementByPath("ReferencedSeriesSequence[2].SeriesInstanceUID", "1.2.3"))
+	private, err := b.FindElementByTag(dicomtag.Tag{Group: 0x0009, Element: 0x1001})
+	require.NoError(t, err)
+	private.Value = []interface{}{"b"}
+	pixels, err := b.FindElementByTag(dicomtag.PixelData)
+	require.NoError(t, err)
+	pixels.Value[0].(dicom.PixelDataInfo).Frames[0][0] = 3
+
+	assert.Equal(t, []string{
+		`changed TransferSyntaxUID: ["1.2.840.10008.1.2"] -> ["1.2.840.10008.1.2.1"]`,
+		`changed ReferencedSeriesSequence[0].ReferencedInstanceSequence[1].ReferencedSOPInstanceUID: ["1.2.1.2"] -> ["1.2.1.3"]`,
+		`added ReferencedSeriesSequence[2]: [item{1 elements}]`,
+		`changed (0009,1001): ["a"] -> ["b"]`,
+		`removed PatientID: ["1234"]`,
+		`added StudyID: ["1"]`,
+		`changed PixelData: [frames{1}] -> [frames{1}]`,
+	}, diffStrings(dicom.Diff(a, b)))
+
+	diffs := dicom.Diff(a, b, dicom.IgnoreMetaElements(), dicom.IgnorePrivateTags(), dicom.IgnorePixelData())
+	assert.Equal(t, []string{
+		`changed ReferencedSeriesSequence[0].ReferencedInstanceSequence[1].ReferencedSOPInstanceUID: ["1.2.1.2"] -> ["1.2.1.3"]`,
+		`added ReferencedSeriesSequence[2]: [item{1 elements}]`,
+		`removed PatientID: ["1234"]`,
+		`added StudyID: ["1"]`,
+	}, diffStrings(diffs))
+	assert.Equal(t, "(0008,1115)[0].(0008,114A)[1].(0008,1155)", diffs[0].Path.String())
+	assert.Nil(t, diffs[1].A)
+	assert.Equal(t, dicomtag.Item, diffs[1].B.Tag)
+
+	// The padding difference is reported only by ExactMatch.
+	diffs = dicom.Diff(a, b, dicom.ExactMatch(), dicom.IgnoreMetaElements(), dicom.IgnorePrivateTags(), dicom.IgnorePixelData())
+	assert.Contains(t, diffStrings(diffs), `changed PatientName: ["Doe^John "] -> ["Doe^John"]`)
+
+	data, err := json.Marshal(dicom.Diff(a, b, dicom.IgnoreMetaElements(), dicom.IgnorePrivateTags(), dicom.IgnorePixelData())[:2])
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"kind": "changed", "path": "(0008,1115)[0].(0008,114A)[1].(0008,1155)",
+		 "name": "ReferencedSeriesSequence[0].ReferencedInstanceSequence[1].ReferencedSOPInstanceUID",
+		 "a": ["\"1.2.1.2\""], "b": ["\"1.2.1.3\""]},
+		{"kind": "added", "path": "(0008,1115)[2]", "name": "ReferencedSeriesSequence[2]", "b": ["item{1 elements}"]}
+	]`, string(data))
+
+	// Elements that follow PixelData are still compared.
+	a.Set(dicom.MustNewElement(dicomtag.DataSetTrailingPadding, []byte{0, 0}))
+	b = a.Clone()
+	b.Set(dicom.MustNewElement(dicomtag.DataSetTrailingPadding, []byte{0, 0, 0, 0}))
+	assert.Equal(t, []string{
+		`changed DataSetTrailingPadding: [bytes{2}] -> [bytes{4}]`,
+	}, diffStrings(dicom.Diff(a, b, dicom.IgnorePixelData())))
+}
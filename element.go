@@ -31,6 +31,9 @@ type Element struct {
 	// Else if VR=="SQ", Value[i] is a *Element, with Tag=TagItem.
 	// Else if VR=="LT", or "UT", then len(Value)==1, and Value[0] is string
 	// Else if VR=="DA", then len(Value)==1, and Value[0] is string. Use ParseDate() to parse the date string.
+	// Else if VR=="TM", then Value[] is a list of strings. Use ParseTime() to parse the time strings.
+	// Else if VR=="DT", then Value[] is a list of strings. Use ParseDateTime() to parse the datetime strings.
+	// Else if VR=="PN", then Value[] is a list of strings. Use ParsePersonName() or GetPersonNames() to split the name components.
 	// Else if VR=="US", Value[] is a list of uint16s (len(Value) matches VM of the Tag; PS 3.5 6.4)
 	// Else if VR=="UL", Value[] is a list of uint32s (len(Value) matches VM of the Tag; PS 3.5 6.4)
 	// Else if VR=="SS", Value[] is a list of int16s (len(Value) matches VM of the Tag; PS 3.5 6.4)
@@ -38,9 +41,14 @@ type Element struct {
 	// Else if VR=="FL", Value[] is a list of float32s (len(Value) matches VM of the Tag; PS 3.5 6.4)
 	// Else if VR=="FD", Value[] is a list of float64s (len(Value) matches VM of the Tag; PS 3.5 6.4)
 	// Else if VR=="AT", Value[] is a list of Tag's. (len(Value) matches VM of the Tag; PS 3.5 6.4)
+	// Else if VR=="SV", Value[] is a list of int64s (len(Value) matches VM of the Tag; PS 3.5 6.4)
+	// Else if VR=="UV", Value[] is a list of uint64s (len(Value) matches VM of the Tag; PS 3.5 6.4)
 	// Else if VR=="OF", Value[] is a list of float32s
 	// Else if VR=="OD", Value[] is a list of float64s
-	// Else if VR=="OW" or "OB", len(Value)==1, and Value[0] is []byte.
+	// Else if VR=="OL", Value[] is a list of uint32s
+	// Else if VR=="OV", Value[] is a list of uint64s
+	// Else if VR=="OW", "OB" or "UN", len(Value)==1, and Value[0] is []byte.
+	// Else if VR=="UR", len(Value)==1, and Value[0] is string
 	// Else, Value[] is a list of strings.
 	//
 	// Note: Use GetVRKind() to map VR string to the go representation of
@@ -73,6 +81,12 @@ type Element struct {
 	// this means.  It's one of the pointless complexities in the DICOM
 	// standard.
 	UndefinedLength bool
+
+	// PrivateCreator is the value of the Private Creator element that
+	// reserves the block of a private data element, e.g., "SIEMENS CSA
+	// HEADER". ReadElement fills this field when the creator is found in the
+	// enclosing dataset or item. It is empty for public elements.
+	PrivateCreator string
 }
 
 // NewElement creates a new Element with the given tag and values. The type of
@@ -108,6 +122,12 @@ func NewElement(tag dicomtag.Tag, values ...interface{}) (*Element, error) {
 			_, ok = v.(float32)
 		case dicomtag.VRFloat64List:
 			_, ok = v.(float64)
+		case dicomtag.VRUInt64List:
+			_, ok = v.(uint64)
+		case dicomtag.VRInt64List:
+			_, ok = v.(int64)
+		case dicomtag.VRString:
+			_, ok = v.(string)
 		case dicomtag.VRPixelData:
 			_, ok = v.(PixelDataInfo)
 		case dicomtag.VRTagList:
@@ -221,6 +241,13 @@ func (e *Element) GetStrings() ([]string, error) {
 	return values, nil
 }
 
+// isSequence checks if the values of the element are sequence items. Besides
+// SQ, that's the case for a UN element of undefined length, which ReadElement
+// parses as a sequence.
+func (e *Element) isSequence() bool {
+	return e.VR == "SQ" || (e.VR == "UN" && e.UndefinedLength)
+}
+
 //return child Elements, nil if no els or el is a val
 func (e *Element) GetElements() []*Element {
 	values := make([]*Element, 0, len(e.Value))
@@ -297,7 +324,7 @@ func elementString(e *Element, nestLevel int) string {
 	if e.UndefinedLength {
 		sVl = "u"
 	}
-	s = fmt.Sprintf("%s %s %s %s ", s, dicomtag.DebugString(e.Tag), e.VR, sVl)
+	s = fmt.Sprintf("%s %s %s %s ", s, dicomtag.DebugStringWithCreator(e.Tag, e.PrivateCreator), e.VR, sVl)
 	if e.VR == "SQ" || e.Tag == dicomtag.Item {
 		s += fmt.Sprintf(" (#%d)[\n", len(e.Value))
 		for _, v := range e.Value {
@@ -329,7 +356,7 @@ func (e *Element) String() string {
 func readRawItem(d *dicomio.Decoder) ([]byte, bool) {
 	tag := readTag(d)
 	// Item is always encoded implicit. PS3.6 7.5
-	vr, vl := readImplicit(d, tag)
+	vr, vl := readImplicit(d, tag, nil)
 	if d.Error() != nil {
 		return nil, true
 	}
@@ -357,7 +384,9 @@ func readRawItem(d *dicomio.Decoder) ([]byte, bool) {
 // PixelDataInfo is the Element.Value payload for PixelData element.
 type PixelDataInfo struct {
 	Offsets []uint32 // BasicOffsetTable
-	Frames  [][]byte // Parsed images
+	// Parsed images. For encapsulated pixel data, each frame is the
+	// concatenation of the fragments that store the image.
+	Frames [][]byte
 }
 
 func (data PixelDataInfo) String() string {
@@ -379,12 +408,12 @@ func readBasicOffsetTable(d *dicomio.Decoder) []uint32 {
 		d.SetErrorf("basic offset table not found")
 	}
 	if len(data) == 0 {
-		return []uint32{0}
+		return nil
 	}
 
 	byteOrder, _ := d.TransferSyntax()
 	// The payload of the item is sequence of uint32s, each representing the
-	// byte size of an image that follows.
+	// byte offset of an image that follows.
 	subdecoder := dicomio.NewBytesDecoder(data, byteOrder, dicomio.ImplicitVR)
 	var offsets []uint32
 	for !subdecoder.EOF() {
@@ -407,6 +436,12 @@ func ParseFileHeader(d *dicomio.Decoder) []*Element {
 		return nil
 	}
 
+	return readMetaElements(d)
+}
+
+// readMetaElements reads the group-length-prefixed meta elements that follow the
+// "DICM" magic word.
+func readMetaElements(d *dicomio.Decoder) []*Element {
 	// (0002,0000) MetaElementGroupLength
 	metaElem := ReadElement(d, ReadOptions{})
 	if d.Error() != nil {
@@ -452,6 +487,13 @@ var endOfDataElement = &Element{Tag: dicomtag.Tag{Group: 0x7fff, Element: 0x7fff
 //
 // - On successful parsing, it returns non-nil and non-endOfDataElement value.
 func ReadElement(d *dicomio.Decoder, options ReadOptions) *Element {
+	return readElement(d, options, nil)
+}
+
+// readElement is similar to ReadElement. "creators" lists the Private Creator
+// elements found so far in the dataset or item that encloses the element. It
+// is used to resolve the VR of private elements in implicit VR encoding.
+func readElement(d *dicomio.Decoder, options ReadOptions, creators dicomtag.PrivateCreators) *Element {
 	tag := readTag(d)
 	if tag == dicomtag.PixelData && options.DropPixelData {
 		return endOfDataElement
@@ -470,7 +512,7 @@ func ReadElement(d *dicomio.Decoder, options ReadOptions) *Element {
 	var vr string // Value Representation
 	var vl uint32 // Value Length
 	if implicit == dicomio.ImplicitVR {
-		vr, vl = readImplicit(d, tag)
+		vr, vl = readImplicit(d, tag, creators)
 	} else {
 		doassert(implicit == dicomio.ExplicitVR, implicit)
 		vr, vl = readExplicit(d, tag)
@@ -482,6 +524,9 @@ func ReadElement(d *dicomio.Decoder, options ReadOptions) *Element {
 		VR:              vr,
 		UndefinedLength: (vl == undefinedLength),
 	}
+	if creatorTag, ok := dicomtag.PrivateCreatorTag(tag); ok {
+		elem.PrivateCreator = creators[creatorTag]
+	}
 	if vr == "UN" && vl == undefinedLength {
 		// This combination appears in some file, but it's unclear what
 		// to do. The standard, as always, is unclear. The best guess is
@@ -492,6 +537,10 @@ func ReadElement(d *dicomio.Decoder, options ReadOptions) *Element {
 		// assuming <UN, undefinedlength> is the same as <SQ, undefined
 		// length>.
 		vr = "SQ"
+		// The items are encoded in implicit little endian regardless of
+		// the transfer syntax of the file. PS3.5 6.2.2, CP-246.
+		d.PushTransferSyntax(binary.LittleEndian, dicomio.ImplicitVR)
+		defer d.PopTransferSyntax()
 	}
 	if tag == dicomtag.PixelData {
 		// P3.5, A.4 describes the format. Currently we only support an encapsulated image format.
@@ -500,22 +549,20 @@ func ReadElement(d *dicomio.Decoder, options ReadOptions) *Element {
 		// the file stores N images, the elements that follow PixelData
 		// are laid out in the following way:
 		//
-		// Item(BasicOffsetTable) Item(PixelDataInfo0) ... Item(PixelDataInfoM) SequenceDelimiterItem
+		// Item(BasicOffsetTable) Item(Fragment0) ... Item(FragmentM) SequenceDelimiterItem
 		//
 		// Item(BasicOffsetTable) is an Item element whose payload
-		// encodes N uint32 values. Kth uint32 is the bytesize of the
-		// Kth image. Item(PixelDataInfo*) are chunked sequences of bytes. I
-		// presume that single PixelDataInfo item doesn't cross a image
-		// boundary, but the spec isn't clear.
+		// encodes N uint32 values. Kth uint32 is the byte offset of the
+		// first fragment of the Kth image, relative to the first byte
+		// of Item(Fragment0). An image may span multiple fragments, but
+		// a fragment doesn't cross an image boundary.
 		//
-		// The total byte size of Item(PixelDataInfo*) equal the total of
-		// the bytesizes found in BasicOffsetTable.
+		// The basic offset table may be empty. In such case, the fragments
+		// are grouped by Parser, which knows NumberOfFrames and the
+		// ExtendedOffsetTable; see inferFrames.
 		if vl == undefinedLength {
 			var image PixelDataInfo
-			image.Offsets = readBasicOffsetTable(d) // TODO(saito) Use the offset table.
-			if len(image.Offsets) > 1 {
-				dicomlog.Vprintf(1, "dicom.ReadElement: Multiple images not supported yet. Combining them into a byte sequence: %v", image.Offsets)
-			}
+			image.Offsets = readBasicOffsetTable(d)
 			for !d.EOF() {
 				chunk, endOfItems := readRawItem(d)
 				if d.Error() != nil {
@@ -526,6 +573,18 @@ func ReadElement(d *dicomio.Decoder, options ReadOptions) *Element {
 				}
 				image.Frames = append(image.Frames, chunk)
 			}
+			if len(image.Offsets) > 0 && len(image.Frames) > 0 {
+				offsets := make([]uint64, len(image.Offsets))
+				for i, offset := range image.Offsets {
+					offsets[i] = uint64(offset)
+				}
+				frames, err := groupFragmentsByOffsets(image.Frames, offsets)
+				if err != nil {
+					dicomlog.Vprintf(1, "dicom.ReadElement: %v. Treating each fragment as a frame", err)
+				} else {
+					image.Frames = frames
+				}
+			}
 			data = append(data, image)
 		} else {
 			dicomlog.Vprintf(1, "dicom.ReadElement: Defined-length pixel data not supported: tag %v, VR=%v, VL=%v", tag.String(), vr, vl)
@@ -533,11 +592,11 @@ func ReadElement(d *dicomio.Decoder, options ReadOptions) *Element {
 			image.Frames = append(image.Frames, d.ReadBytes(int(vl)))
 			data = append(data, image)
 		}
-		// TODO(saito) handle multi-frame image.
 	} else if vr == "SQ" {
 		// Note: when reading subitems inside sequence or item, we ignore
 		// DropPixelData and other shortcircuiting options. If we honored them, we'd
 		// be unable to read the rest of the file.
+		itemOptions := ReadOptions{CP1250Fix: options.CP1250Fix}
 		if vl == undefinedLength {
 			// Format:
 			//  Sequence := ItemSet* SequenceDelimitationItem
@@ -545,7 +604,7 @@ func ReadElement(d *dicomio.Decoder, options ReadOptions) *Element {
 			//             Item Any*N                     (when Item.VL has a defined value)
 			for {
 				// Makes sure to return all sub elements even if the tag is not in the return tags list of options or is greater than the Stop At Tag
-				item := ReadElement(d, ReadOptions{})
+				item := ReadElement(d, itemOptions)
 				if d.Error() != nil {
 					break
 				}
@@ -565,7 +624,7 @@ func ReadElement(d *dicomio.Decoder, options ReadOptions) *Element {
 			d.PushLimit(int64(vl))
 			for !d.EOF() {
 				// Makes sure to return all sub elements even if the tag is not in the return tags list of options or is greater than the Stop At Tag
-				item := ReadElement(d, ReadOptions{})
+				item := ReadElement(d, itemOptions)
 				if d.Error() != nil {
 					break
 				}
@@ -578,17 +637,25 @@ func ReadElement(d *dicomio.Decoder, options ReadOptions) *Element {
 			d.PopLimit()
 		}
 	} else if tag == dicomtag.Item { // Item (component of SQ)
+		// Private blocks and SpecificCharacterSet are scoped to the
+		// item. P3.5 7.5.1, 7.8.1.
+		itemCreators := dicomtag.PrivateCreators{}
+		itemOptions := ReadOptions{CP1250Fix: options.CP1250Fix}
+		d.PushCodingSystem()
+		defer d.PopCodingSystem()
 		if vl == undefinedLength {
 			// Format: Item Any* ItemDelimitationItem
 			for {
 				// Makes sure to return all sub elements even if the tag is not in the return tags list of options or is greater than the Stop At Tag
-				subelem := ReadElement(d, ReadOptions{})
+				subelem := readElement(d, itemOptions, itemCreators)
 				if d.Error() != nil {
 					break
 				}
 				if subelem.Tag == dicomtag.ItemDelimitationItem {
 					break
 				}
+				recordPrivateCreator(itemCreators, subelem)
+				setSpecificCharacterSet(d, subelem, options.CP1250Fix)
 				data = append(data, subelem)
 			}
 		} else {
@@ -596,10 +663,12 @@ func ReadElement(d *dicomio.Decoder, options ReadOptions) *Element {
 			d.PushLimit(int64(vl))
 			for !d.EOF() {
 				// Makes sure to return all sub elements even if the tag is not in the return tags list of options or is greater than the Stop At Tag
-				subelem := ReadElement(d, ReadOptions{})
+				subelem := readElement(d, itemOptions, itemCreators)
 				if d.Error() != nil {
 					break
 				}
+				recordPrivateCreator(itemCreators, subelem)
+				setSpecificCharacterSet(d, subelem, options.CP1250Fix)
 				data = append(data, subelem)
 			}
 			d.PopLimit()
@@ -636,14 +705,18 @@ func ReadElement(d *dicomio.Decoder, options ReadOptions) *Element {
 				// TODO(saito) If OB's length is odd, is VL odd too? Need to check!
 				data = append(data, e.Bytes())
 			}
-		} else if vr == "OB" {
+		} else if vr == "OB" || vr == "UN" {
 			// TODO(saito) Check that size is even. Byte swap??
 			// TODO(saito) If OB's length is odd, is VL odd too? Need to check!
 			data = append(data, d.ReadBytes(int(vl)))
 		} else if vr == "LT" || vr == "UT" {
 			str := d.ReadString(int(vl))
 			data = append(data, str)
-		} else if vr == "UL" {
+		} else if vr == "UR" {
+			// Trailing spaces are insignificant. PS3.5 6.2.
+			str := strings.TrimRight(d.ReadString(int(vl)), " ")
+			data = append(data, str)
+		} else if vr == "UL" || vr == "OL" {
 			for !d.EOF() {
 				data = append(data, d.ReadUInt32())
 			}
@@ -659,6 +732,14 @@ func ReadElement(d *dicomio.Decoder, options ReadOptions) *Element {
 			for !d.EOF() {
 				data = append(data, d.ReadInt16())
 			}
+		} else if vr == "UV" || vr == "OV" {
+			for !d.EOF() {
+				data = append(data, d.ReadUInt64())
+			}
+		} else if vr == "SV" {
+			for !d.EOF() {
+				data = append(data, d.ReadInt64())
+			}
 		} else if vr == "FL" || vr == "OF" {
 			for !d.EOF() {
 				data = append(data, d.ReadFloat32())
@@ -669,7 +750,12 @@ func ReadElement(d *dicomio.Decoder, options ReadOptions) *Element {
 			}
 		} else {
 			// List of strings, each delimited by '\\'.
-			v := d.ReadString(int(vl))
+			var v string
+			if vr == "PN" {
+				v = d.ReadPersonName(int(vl))
+			} else {
+				v = d.ReadString(int(vl))
+			}
 			// String may have '\0' suffix if its length is odd.
 			str := strings.Trim(v, " \000")
 			if len(str) > 0 {
@@ -693,11 +779,27 @@ func readTag(buffer *dicomio.Decoder) dicomtag.Tag {
 	return dicomtag.Tag{group, element}
 }
 
-// Read the VR from the DICOM ditionary The VL is a 32-bit unsigned integer
-func readImplicit(buffer *dicomio.Decoder, tag dicomtag.Tag) (string, uint32) {
+// recordPrivateCreator adds "elem" to "creators" if it is a Private Creator
+// element.
+func recordPrivateCreator(creators dicomtag.PrivateCreators, elem *Element) {
+	if !dicomtag.IsPrivateCreator(elem.Tag) {
+		return
+	}
+	if s, err := elem.GetString(); err == nil {
+		creators[elem.Tag] = strings.TrimSpace(s)
+	}
+}
+
+// Read the VR from the DICOM ditionary The VL is a 32-bit unsigned integer.
+// The VR of a private element is looked up in the private dictionary using
+// "creators".
+func readImplicit(buffer *dicomio.Decoder, tag dicomtag.Tag, creators dicomtag.PrivateCreators) (string, uint32) {
 	vr := "UN"
-	if entry, err := dicomtag.Find(tag); err == nil {
+	if entry, err := creators.Find(tag); err == nil {
 		vr = entry.VR
+	} else if dicomtag.IsPrivateCreator(tag) {
+		// Private Creator elements are always LO. PS3.5 7.8.1.
+		vr = "LO"
 	}
 
 	vl := buffer.ReadUInt32()
@@ -716,7 +818,7 @@ func readExplicit(buffer *dicomio.Decoder, tag dicomtag.Tag) (string, uint32) {
 	switch vr {
 	// TODO(saito) The case list below differs from Table 7.1.1 in PS 3.5
 	// (http://dicom.nema.org/Dicom/2013/output/chtml/part05/chapter_7.html#table_7.1-1).
-	case "NA", "OB", "OD", "OF", "OL", "OW", "SQ", "UN", "UC", "UR", "UT":
+	case "NA", "OB", "OD", "OF", "OL", "OV", "OW", "SQ", "SV", "UN", "UC", "UR", "UT", "UV":
 		buffer.Skip(2) // ignore two bytes for "future use" (0000H)
 		vl = buffer.ReadUInt32()
 		if vl == undefinedLength && (vr == "UC" || vr == "UR" || vr == "UT") {
@@ -0,0 +1,223 @@
+package dicom
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Equal checks if the two elements have the same tag, VR, private creator and
+// values. Items are compared recursively, and like DataSet.Equal, regardless
+// of the order of elements in them. UndefinedLength is ignored, since it's a
+// matter of encoding.
+func (e *Element) Equal(other *Element) bool {
+	if e.Tag != other.Tag || e.VR != other.VR || e.PrivateCreator != other.PrivateCreator ||
+		len(e.Value) != len(other.Value) {
+		return false
+	}
+	for i := range e.Value {
+		if !valueEqual(e.Value[i], other.Value[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func valueEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case []byte:
+		b, ok := b.([]byte)
+		return ok && bytes.Equal(a, b)
+	case *Element:
+		b, ok := b.(*Element)
+		return ok && a.Tag == b.Tag && a.VR == b.VR && elementsEqual(a.GetElements(), b.GetElements(), (*Element).Equal)
+	case PixelDataInfo:
+		b, ok := b.(PixelDataInfo)
+		return ok && reflect.DeepEqual(a.Offsets, b.Offsets) && framesEqual(a.Frames, b.Frames)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func framesEqual(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// EqualFold is similar to Equal, but it checks whether the two elements mean
+// the same thing rather than whether they are represented the same way. In
+// particular:
+//
+//   - VR, PrivateCreator and UndefinedLength are ignored.
+//   - Trailing spaces and NULs of string values are ignored, as are leading
+//     spaces except for ST, LT and UT. An element with only empty strings
+//     equals one with no values.
+//   - Numbers are compared by value, regardless of their Go type. DS and IS
+//     strings are parsed as numbers, so "1.50" equals float64(1.5).
+//   - A []byte that differs only by a trailing padding NUL is equal.
+//   - Items are compared element by element, regardless of the order of
+//     elements in them.
+//   - PixelDataInfo is compared by frames; the basic offset table is ignored.
+func (e *Element) EqualFold(other *Element) bool {
+	if e.Tag != other.Tag {
+		return false
+	}
+	a, b := foldValues(e), foldValues(other)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !valueEqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// foldValues normalizes the values of "e" for EqualFold. DS and IS strings are
+// converted to float64 when possible.
+func foldValues(e *Element) []interface{} {
+	values := make([]interface{}, len(e.Value))
+	allEmpty := true
+	for i, v := range e.Value {
+		if s, ok := v.(string); ok {
+			s = strings.TrimRight(s, " \x00")
+			if e.VR != "ST" && e.VR != "LT" && e.VR != "UT" {
+				s = strings.TrimLeft(s, " ")
+			}
+			v = s
+			if e.VR == "DS" || e.VR == "IS" {
+				if f, err := ParseDS(s); err == nil {
+					v = f
+				}
+			}
+			if s != "" {
+				allEmpty = false
+			}
+		} else {
+			allEmpty = false
+		}
+		values[i] = v
+	}
+	if allEmpty {
+		return nil
+	}
+	return values
+}
+
+func valueEqualFold(a, b interface{}) bool {
+	switch a := a.(type) {
+	case []byte:
+		b, ok := b.([]byte)
+		if !ok {
+			return false
+		}
+		if len(a) > len(b) {
+			a, b = b, a
+		}
+		return bytes.Equal(a, b) || (len(b) == len(a)+1 && b[len(a)] == 0 && bytes.Equal(a, b[:len(a)]))
+	case *Element:
+		b, ok := b.(*Element)
+		return ok && a.Tag == b.Tag && elementsEqual(a.GetElements(), b.GetElements(), (*Element).EqualFold)
+	case PixelDataInfo:
+		b, ok := b.(PixelDataInfo)
+		return ok && framesEqual(a.Frames, b.Frames)
+	}
+	ra, rb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if isNumberKind(ra.Kind()) && isNumberKind(rb.Kind()) {
+		if isIntKind(ra.Kind()) && isIntKind(rb.Kind()) {
+			// Exact, even for uint64 values that don't fit in float64.
+			return fmt.Sprint(a) == fmt.Sprint(b)
+		}
+		return toFloat64(ra) == toFloat64(rb)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	return isIntKind(k) || k == reflect.Float32 || k == reflect.Float64
+}
+
+func toFloat64(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	}
+	return float64(v.Int())
+}
+
+// elementsEqual checks if "a" and "b" have the same multiset of tags, and the
+// elements of each tag can be paired so that they are equal according to "eq".
+// A tag may appear more than once, e.g., in a dataset read from a malformed
+// file.
+func elementsEqual(a, b []*Element, eq func(x, y *Element) bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = sortedByTag(a), sortedByTag(b)
+	for start := 0; start < len(a); {
+		end := start + 1
+		for end < len(a) && a[end].Tag == a[start].Tag {
+			end++
+		}
+		// b[start:end] must hold exactly the elements of the same tag.
+		tag := a[start].Tag
+		if b[start].Tag != tag || b[end-1].Tag != tag || (end < len(b) && b[end].Tag == tag) {
+			return false
+		}
+		// Pair each element of a[start:end] with an unused equal one.
+		used := make([]bool, end-start)
+		for _, elem := range a[start:end] {
+			found := false
+			for i, other := range b[start:end] {
+				if !used[i] && eq(elem, other) {
+					used[i], found = true, true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		start = end
+	}
+	return true
+}
+
+// sortedByTag returns a copy of "elems" sorted by tag.
+func sortedByTag(elems []*Element) []*Element {
+	sorted := append([]*Element(nil), elems...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Tag.Compare(sorted[j].Tag) < 0 })
+	return sorted
+}
+
+// Equal checks if the two datasets have the same elements, as defined by
+// Element.Equal. The order of elements doesn't matter.
+func (f *DataSet) Equal(other *DataSet) bool {
+	return elementsEqual(f.Elements, other.Elements, (*Element).Equal)
+}
+
+// EqualFold checks if the two datasets have the same elements, as defined by
+// Element.EqualFold. The order of elements doesn't matter.
+func (f *DataSet) EqualFold(other *DataSet) bool {
+	return elementsEqual(f.Elements, other.Elements, (*Element).EqualFold)
+}
@@ -0,0 +1,150 @@
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/msz-kp/go-dicom/dicomio"
+	"github.com/msz-kp/go-dicom/dicomtag"
+)
+
+// FileMeta is the file meta information group (Tag.Group==2) of a DICOM file.
+// P3.10 7.1.
+type FileMeta struct {
+	// FileMetaInformationVersion, usually {0, 1}.
+	Version []byte
+	// MediaStorageSOPClassUID, e.g., "1.2.840.10008.5.1.4.1.1.2" for CT.
+	SOPClassUID string
+	// MediaStorageSOPInstanceUID.
+	SOPInstanceUID string
+	// TransferSyntaxUID of the dataset that follows the meta group.
+	TransferSyntaxUID string
+	// ImplementationClassUID and ImplementationVersionName of the
+	// application that wrote the file.
+	ImplementationClassUID    string
+	ImplementationVersionName string
+	// SourceApplicationEntityTitle, the AE title of the application that
+	// sent or created the file.
+	SourceApplicationEntityTitle string
+	// Meta elements not covered by the above fields, e.g.,
+	// PrivateInformationCreatorUID.
+	Other []*Element
+}
+
+// NewFileMeta extracts the FileMeta from the meta elements, such as those
+// returned by ParseFileHeader. Elements outside group 2 are ignored. It
+// returns an error if the elements lack TransferSyntaxUID.
+func NewFileMeta(metaElems []*Element) (*FileMeta, error) {
+	meta := &FileMeta{}
+	for _, elem := range metaElems {
+		if elem.Tag.Group != dicomtag.MetadataGroup {
+			continue
+		}
+		var err error
+		switch elem.Tag {
+		case dicomtag.FileMetaInformationGroupLength:
+			// Recomputed by WriteFileMeta.
+		case dicomtag.FileMetaInformationVersion:
+			if len(elem.Value) == 1 {
+				meta.Version, _ = elem.Value[0].([]byte)
+			}
+		case dicomtag.MediaStorageSOPClassUID:
+			meta.SOPClassUID, err = elem.GetString()
+		case dicomtag.MediaStorageSOPInstanceUID:
+			meta.SOPInstanceUID, err = elem.GetString()
+		case dicomtag.TransferSyntaxUID:
+			meta.TransferSyntaxUID, err = elem.GetString()
+		case dicomtag.ImplementationClassUID:
+			meta.ImplementationClassUID, err = elem.GetString()
+		case dicomtag.ImplementationVersionName:
+			meta.ImplementationVersionName, err = elem.GetString()
+		case dicomtag.SourceApplicationEntityTitle:
+			meta.SourceApplicationEntityTitle, err = elem.GetString()
+		default:
+			meta.Other = append(meta.Other, elem)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if meta.TransferSyntaxUID == "" {
+		return nil, fmt.Errorf("%v not found in meta elements", dicomtag.DebugString(dicomtag.TransferSyntaxUID))
+	}
+	return meta, nil
+}
+
+// Elements converts the FileMeta back to meta elements. Empty fields are
+// omitted.
+func (m *FileMeta) Elements() []*Element {
+	var elems []*Element
+	if len(m.Version) > 0 {
+		elems = append(elems, MustNewElement(dicomtag.FileMetaInformationVersion, m.Version))
+	}
+	for _, f := range []struct {
+		tag   dicomtag.Tag
+		value string
+	}{
+		{dicomtag.MediaStorageSOPClassUID, m.SOPClassUID},
+		{dicomtag.MediaStorageSOPInstanceUID, m.SOPInstanceUID},
+		{dicomtag.TransferSyntaxUID, m.TransferSyntaxUID},
+		{dicomtag.ImplementationClassUID, m.ImplementationClassUID},
+		{dicomtag.ImplementationVersionName, m.ImplementationVersionName},
+		{dicomtag.SourceApplicationEntityTitle, m.SourceApplicationEntityTitle},
+	} {
+		if f.value != "" {
+			elems = append(elems, MustNewElement(f.tag, f.value))
+		}
+	}
+	return append(elems, m.Other...)
+}
+
+// WriteFileMeta writes the DICOM file header: the preamble, the "DICM" magic
+// word and the meta elements of "meta". SOPClassUID, SOPInstanceUID and
+// TransferSyntaxUID are required. Version, ImplementationClassUID and
+// ImplementationVersionName default to those of go-dicom if empty. It replaces
+// WriteFileHeader.
+//
+// Errors are reported via e.Error().
+func WriteFileMeta(e *dicomio.Encoder, meta *FileMeta, opts *WriteOptSet) {
+	writeFileHeader(e, meta.Elements(), opts)
+}
+
+// ReadFileMeta reads the file meta information group from "in" and returns it.
+// For a DICOM Part 10 file, it consumes exactly the preamble, the "DICM" magic
+// word and the meta elements, so "in" is positioned at the first dataset
+// element on return. It's much cheaper than ReadDataSet when only the SOP
+// class, transfer syntax and such are needed.
+//
+// Like NewParser, it also accepts input without a file header, in which case
+// the transfer syntax is guessed, and "in" may be read past the meta group.
+func ReadFileMeta(in io.Reader) (*FileMeta, error) {
+	// Preamble, "DICM", and the FileMetaInformationGroupLength element,
+	// which is always in explicit little endian.
+	head := make([]byte, 128+4+12)
+	n, err := io.ReadFull(in, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	var d *dicomio.Decoder
+	if n == len(head) && string(head[128:132]) == "DICM" &&
+		binary.LittleEndian.Uint16(head[132:]) == dicomtag.MetadataGroup &&
+		binary.LittleEndian.Uint16(head[134:]) == dicomtag.FileMetaInformationGroupLength.Element {
+		buf := bytes.NewBuffer(head)
+		if _, err := io.CopyN(buf, in, int64(binary.LittleEndian.Uint32(head[140:]))); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		d = dicomio.NewBytesDecoder(buf.Bytes(), binary.LittleEndian, dicomio.ExplicitVR)
+	} else {
+		d = dicomio.NewDecoder(io.MultiReader(bytes.NewReader(head[:n]), in), binary.LittleEndian, dicomio.ExplicitVR)
+	}
+	metaElems := readFileHeader(d)
+	if d.Error() != nil {
+		return nil, d.Error()
+	}
+	return NewFileMeta(metaElems)
+}
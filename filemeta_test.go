@@ -0,0 +1,106 @@
+package dicom_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+
+	"github.com/msz-kp/go-dicom"
+	"github.com/msz-kp/go-dicom/dicomio"
+	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/msz-kp/go-dicom/dicomuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFileMeta(t *testing.T) {
+	data, err := ioutil.ReadFile("examples/IM-0001-0001.dcm")
+	require.NoError(t, err)
+	in := bytes.NewReader(data)
+	meta, err := dicom.ReadFileMeta(in)
+	require.NoError(t, err)
+
+	ds := mustReadFile(t, "examples/IM-0001-0001.dcm", dicom.ReadOptions{})
+	for _, f := range []struct {
+		tag   dicomtag.Tag
+		value string
+	}{
+		{dicomtag.MediaStorageSOPClassUID, meta.SOPClassUID},
+		{dicomtag.MediaStorageSOPInstanceUID, meta.SOPInstanceUID},
+		{dicomtag.TransferSyntaxUID, meta.TransferSyntaxUID},
+		{dicomtag.ImplementationClassUID, meta.ImplementationClassUID},
+	} {
+		elem, err := ds.FindElementByTag(f.tag)
+		require.NoError(t, err)
+		assert.Equal(t, elem.MustGetString(), f.value, dicomtag.DebugString(f.tag))
+	}
+	assert.Equal(t, []byte{0, 1}, meta.Version)
+
+	// The reader is positioned right after the meta group.
+	head := make([]byte, 2)
+	_, err = in.Read(head)
+	require.NoError(t, err)
+	assert.NotEqual(t, dicomtag.MetadataGroup, binary.LittleEndian.Uint16(head))
+	assert.Equal(t, len(data)-in.Len()-2, int(ds.Elements[0].MustGetUInt32())+12+132)
+}
+
+func TestWriteFileMeta(t *testing.T) {
+	meta := &dicom.FileMeta{
+		SOPClassUID:                  "1.2.840.10008.5.1.4.1.1.7",
+		SOPInstanceUID:               "1.2.3.4.5.6.7",
+		TransferSyntaxUID:            dicomuid.ExplicitVRLittleEndian,
+		SourceApplicationEntityTitle: "STORESCU",
+		Other: []*dicom.Element{
+			dicom.MustNewElement(dicomtag.PrivateInformationCreatorUID, "1.2.3.4"),
+		},
+	}
+	e := dicomio.NewBytesEncoder(binary.LittleEndian, dicomio.ExplicitVR)
+	dicom.WriteFileMeta(e, meta, &dicom.WriteOptSet{})
+	require.NoError(t, e.Error())
+
+	meta2, err := dicom.ReadFileMeta(bytes.NewReader(e.Bytes()))
+	require.NoError(t, err)
+	// WriteFileMeta fills in the defaults.
+	assert.NotEmpty(t, meta2.Version)
+	assert.Equal(t, dicom.GoDICOMImplementationClassUID, meta2.ImplementationClassUID)
+	assert.Equal(t, dicom.GoDICOMImplementationVersionName, meta2.ImplementationVersionName)
+	meta2.Version, meta2.ImplementationClassUID, meta2.ImplementationVersionName = nil, "", ""
+	assert.Equal(t, meta.Elements()[0:4], meta2.Elements()[0:4])
+	assert.Equal(t, "1.2.3.4", meta2.Other[0].MustGetString())
+
+	// A FileMeta read from a file survives a round trip.
+	data, err := ioutil.ReadFile("examples/IM-0001-0001.dcm")
+	require.NoError(t, err)
+	meta, err = dicom.ReadFileMeta(bytes.NewReader(data))
+	require.NoError(t, err)
+	e = dicomio.NewBytesEncoder(binary.LittleEndian, dicomio.ExplicitVR)
+	dicom.WriteFileMeta(e, meta, &dicom.WriteOptSet{})
+	require.NoError(t, e.Error())
+	meta2, err = dicom.ReadFileMeta(bytes.NewReader(e.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, meta, meta2)
+}
+
+func TestReadFileMetaErrors(t *testing.T) {
+	_, err := dicom.ReadFileMeta(bytes.NewReader(nil))
+	assert.Error(t, err)
+	_, err = dicom.ReadFileMeta(bytes.NewReader([]byte("not a dicom file")))
+	assert.Error(t, err)
+
+	// The meta group is cut short.
+	e := dicomio.NewBytesEncoder(binary.LittleEndian, dicomio.ExplicitVR)
+	dicom.WriteFileHeader(e, []*dicom.Element{
+		dicom.MustNewElement(dicomtag.TransferSyntaxUID, dicomuid.ExplicitVRLittleEndian),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPClassUID, "1.2.840.10008.5.1.4.1.1.7"),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPInstanceUID, "1.2.3.4.5.6.7"),
+	}, &dicom.WriteOptSet{})
+	data := e.Bytes()
+	_, err = dicom.ReadFileMeta(bytes.NewReader(data[:len(data)-4]))
+	assert.Error(t, err)
+
+	_, err = dicom.NewFileMeta([]*dicom.Element{
+		dicom.MustNewElement(dicomtag.MediaStorageSOPClassUID, "1.2.840.10008.5.1.4.1.1.7"),
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,156 @@
+package dicom
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/msz-kp/go-dicom/dicomtag"
+)
+
+// Encapsulated pixel data is stored as a list of fragments, i.e., the items
+// that follow the basic offset table, and a frame may span several
+// fragments. P3.5 A.4. The functions below group fragments into frames.
+
+// itemHeaderSize is the size of the tag and length that precede each fragment.
+const itemHeaderSize = 8
+
+// fragmentOffsets returns the byte offset of each fragment, measured from the
+// first byte of the first fragment's item header, as used by the basic and
+// extended offset tables.
+func fragmentOffsets(fragments [][]byte) []uint64 {
+	offsets := make([]uint64, len(fragments))
+	var offset uint64
+	for i, fragment := range fragments {
+		offsets[i] = offset
+		offset += itemHeaderSize + uint64(len(fragment))
+	}
+	return offsets
+}
+
+// groupFragmentsByOffsets groups the fragments into frames, using the frame
+// offsets found in the basic or the extended offset table. Each offset must
+// point to the start of a fragment.
+func groupFragmentsByOffsets(fragments [][]byte, frameOffsets []uint64) ([][]byte, error) {
+	if len(frameOffsets) == 0 || frameOffsets[0] != 0 {
+		return nil, fmt.Errorf("offset table %v doesn't start with 0", frameOffsets)
+	}
+	starts := make([]bool, len(fragments))
+	fragOffsets := fragmentOffsets(fragments)
+	j := 0
+	for i, offset := range frameOffsets {
+		if i > 0 && offset <= frameOffsets[i-1] {
+			return nil, fmt.Errorf("offset table %v is not in ascending order", frameOffsets)
+		}
+		for j < len(fragOffsets) && fragOffsets[j] < offset {
+			j++
+		}
+		if j == len(fragOffsets) || fragOffsets[j] != offset {
+			return nil, fmt.Errorf("offset %d in offset table doesn't point to a fragment", offset)
+		}
+		starts[j] = true
+	}
+	return groupFragments(fragments, starts), nil
+}
+
+// groupFragments concatenates fragments into frames. starts[i] is true iff the
+// i'th fragment starts a new frame; the first fragment always does, and
+// fragments beyond len(starts) never do. A frame that consists of a single
+// fragment shares its memory.
+func groupFragments(fragments [][]byte, starts []bool) [][]byte {
+	var frames [][]byte
+	begin := 0
+	for i := 1; i <= len(fragments); i++ {
+		if i < len(fragments) && !(i < len(starts) && starts[i]) {
+			continue
+		}
+		if i-begin == 1 {
+			frames = append(frames, fragments[begin])
+		} else {
+			frames = append(frames, bytes.Join(fragments[begin:i], nil))
+		}
+		begin = i
+	}
+	return frames
+}
+
+// Markers that start a JPEG (including JPEG-LS) or a JPEG 2000 codestream, and
+// that end either.
+var (
+	jpegStartMarker     = []byte{0xff, 0xd8}
+	jpeg2000StartMarker = []byte{0xff, 0x4f, 0xff, 0x51}
+	endOfImageMarker    = []byte{0xff, 0xd9}
+)
+
+// groupFragmentsByMarkers groups the fragments into numFrames frames by
+// looking for the start-of-image markers at the beginning of fragments, and
+// if that fails, for the end-of-image markers at the end of fragments.
+func groupFragmentsByMarkers(fragments [][]byte, numFrames int) ([][]byte, error) {
+	startsByStart := make([]bool, len(fragments))
+	startsByEnd := make([]bool, len(fragments))
+	// Number of frames found by each method.
+	nByStart, nByEnd := 1, 1
+	for i, fragment := range fragments {
+		if i > 0 && (bytes.HasPrefix(fragment, jpegStartMarker) || bytes.HasPrefix(fragment, jpeg2000StartMarker)) {
+			startsByStart[i] = true
+			nByStart++
+		}
+		// Fragments are padded to an even length.
+		if i+1 < len(fragments) && bytes.HasSuffix(bytes.TrimRight(fragment, "\x00"), endOfImageMarker) {
+			startsByEnd[i+1] = true
+			nByEnd++
+		}
+	}
+	if nByStart == numFrames {
+		return groupFragments(fragments, startsByStart), nil
+	}
+	if nByEnd == numFrames {
+		return groupFragments(fragments, startsByEnd), nil
+	}
+	return nil, fmt.Errorf("can't find %d frames in %d fragments", numFrames, len(fragments))
+}
+
+// inferFrames groups the fragments of pixel data whose basic offset table is
+// empty. extendedOffsets is the ExtendedOffsetTable, if any, and numFrames is
+// the value of NumberOfFrames, or 0 if it's missing. Without either, all the
+// fragments form a single frame.
+func inferFrames(fragments [][]byte, extendedOffsets []uint64, numFrames int) ([][]byte, error) {
+	if len(fragments) == 0 {
+		return fragments, nil
+	}
+	if len(extendedOffsets) > 0 {
+		return groupFragmentsByOffsets(fragments, extendedOffsets)
+	}
+	if numFrames <= 1 {
+		return groupFragments(fragments, nil), nil
+	}
+	if numFrames == len(fragments) {
+		return fragments, nil
+	}
+	return groupFragmentsByMarkers(fragments, numFrames)
+}
+
+// encapsulatedFrames returns the frames of an encapsulated PixelData element,
+// or nil if the pixel data isn't encapsulated.
+func encapsulatedFrames(elem *Element) [][]byte {
+	if !elem.UndefinedLength || len(elem.Value) != 1 {
+		return nil
+	}
+	image, ok := elem.Value[0].(PixelDataInfo)
+	if !ok || image.Frames == nil {
+		return nil
+	}
+	return image.Frames
+}
+
+// extendedOffsetTable returns the ExtendedOffsetTable and
+// ExtendedOffsetTableLengths elements for frames that are stored one fragment
+// per frame.
+func extendedOffsetTable(frames [][]byte) (offsets, lengths *Element) {
+	offsets = &Element{Tag: dicomtag.ExtendedOffsetTable, VR: "OV"}
+	lengths = &Element{Tag: dicomtag.ExtendedOffsetTableLengths, VR: "OV"}
+	for i, offset := range fragmentOffsets(frames) {
+		offsets.Value = append(offsets.Value, offset)
+		lengths.Value = append(lengths.Value, uint64(len(frames[i])))
+	}
+	return offsets, lengths
+}
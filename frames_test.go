@@ -0,0 +1,182 @@
+package dicom_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/msz-kp/go-dicom"
+	"github.com/msz-kp/go-dicom/dicomio"
+	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeEncapsulated writes a JPEG baseline file with the given elements,
+// followed by encapsulated pixel data that stores "offsets" and "fragments" as
+// is. WriteDataSet can't be used, since it writes one fragment per frame.
+func writeEncapsulated(t *testing.T, offsets []uint32, fragments [][]byte, elems ...*dicom.Element) []byte {
+	var buf bytes.Buffer
+	e := dicomio.NewEncoder(&buf, binary.LittleEndian, dicomio.ExplicitVR)
+	dicom.WriteFileHeader(e, []*dicom.Element{
+		dicom.MustNewElement(dicomtag.TransferSyntaxUID, "1.2.840.10008.1.2.4.50"),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPClassUID, "1.2.840.10008.5.1.4.1.1.7"),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPInstanceUID, "1.2.3.4"),
+	}, &dicom.WriteOptSet{})
+	for _, elem := range elems {
+		dicom.WriteElement(e, elem, &dicom.WriteOptSet{})
+	}
+	writeItem := func(group, element uint16, data []byte) {
+		e.WriteUInt16(group)
+		e.WriteUInt16(element)
+		e.WriteUInt32(uint32(len(data)))
+		e.WriteBytes(data)
+	}
+	e.WriteUInt16(dicomtag.PixelData.Group)
+	e.WriteUInt16(dicomtag.PixelData.Element)
+	e.WriteString("OB")
+	e.WriteZeros(2)
+	e.WriteUInt32(0xffffffff)
+	table := make([]byte, 4*len(offsets))
+	for i, offset := range offsets {
+		binary.LittleEndian.PutUint32(table[4*i:], offset)
+	}
+	writeItem(dicomtag.Item.Group, dicomtag.Item.Element, table)
+	for _, fragment := range fragments {
+		writeItem(dicomtag.Item.Group, dicomtag.Item.Element, fragment)
+	}
+	writeItem(dicomtag.SequenceDelimitationItem.Group, dicomtag.SequenceDelimitationItem.Element, nil)
+	require.NoError(t, e.Error())
+	return buf.Bytes()
+}
+
+func readPixelDataInfo(t *testing.T, ds *dicom.DataSet) dicom.PixelDataInfo {
+	elem, err := ds.FindElementByTag(dicomtag.PixelData)
+	require.NoError(t, err)
+	return elem.Value[0].(dicom.PixelDataInfo)
+}
+
+// readEncapsulatedFrames returns the frames read back from the file created
+// by writeEncapsulated.
+func readEncapsulatedFrames(t *testing.T, offsets []uint32, fragments [][]byte, elems ...*dicom.Element) [][]byte {
+	ds, err := dicom.ReadDataSetInBytes(writeEncapsulated(t, offsets, fragments, elems...), dicom.ReadOptions{})
+	require.NoError(t, err)
+	return readPixelDataInfo(t, ds).Frames
+}
+
+// rewrite writes the dataset with WriteDataSet and reads it back.
+func rewrite(t *testing.T, ds *dicom.DataSet) *dicom.DataSet {
+	var buf bytes.Buffer
+	require.NoError(t, dicom.WriteDataSet(&buf, ds))
+	ds2, err := dicom.ReadDataSetInBytes(buf.Bytes(), dicom.ReadOptions{})
+	require.NoError(t, err)
+	return ds2
+}
+
+func TestReadFramesWithBasicOffsetTable(t *testing.T) {
+	fragments := [][]byte{{1, 2}, {3, 4}, {5, 6, 7, 8}}
+	// The second frame starts at the third fragment: 2*(8+2) bytes.
+	frames := readEncapsulatedFrames(t, []uint32{0, 20}, fragments)
+	assert.Equal(t, [][]byte{{1, 2, 3, 4}, {5, 6, 7, 8}}, frames)
+
+	// An offset that doesn't point to a fragment is ignored.
+	frames = readEncapsulatedFrames(t, []uint32{0, 12}, fragments)
+	assert.Equal(t, fragments, frames)
+}
+
+func TestReadFramesWithExtendedOffsetTable(t *testing.T) {
+	fragments := [][]byte{{1, 2}, {3, 4}, {5, 6}, {7, 8}}
+	frames := readEncapsulatedFrames(t, nil, fragments,
+		dicom.MustNewElement(dicomtag.NumberOfFrames, "2"),
+		dicom.MustNewElement(dicomtag.ExtendedOffsetTable, uint64(0), uint64(30)),
+		dicom.MustNewElement(dicomtag.ExtendedOffsetTableLengths, uint64(6), uint64(2)))
+	assert.Equal(t, [][]byte{{1, 2, 3, 4, 5, 6}, {7, 8}}, frames)
+}
+
+func TestReadFramesWithoutOffsetTable(t *testing.T) {
+	jpeg := func(b ...byte) []byte { return append([]byte{0xff, 0xd8}, b...) }
+	fragments := [][]byte{jpeg(1, 2), {3, 4, 0xff, 0xd9}, jpeg(5, 6), {7, 8}, {0xff, 0xd9}}
+
+	// Without NumberOfFrames, the fragments form a single frame.
+	frames := readEncapsulatedFrames(t, nil, fragments[:2])
+	assert.Equal(t, [][]byte{{0xff, 0xd8, 1, 2, 3, 4, 0xff, 0xd9}}, frames)
+
+	// Frames are found by the start of image markers.
+	frames = readEncapsulatedFrames(t, nil, fragments,
+		dicom.MustNewElement(dicomtag.NumberOfFrames, "2"))
+	assert.Equal(t, [][]byte{
+		{0xff, 0xd8, 1, 2, 3, 4, 0xff, 0xd9},
+		{0xff, 0xd8, 5, 6, 7, 8, 0xff, 0xd9},
+	}, frames)
+
+	// Frames are found by the end of image markers, since the second frame
+	// doesn't start with a marker.
+	fragments[2] = []byte{5, 6}
+	frames = readEncapsulatedFrames(t, nil, fragments,
+		dicom.MustNewElement(dicomtag.NumberOfFrames, "2"))
+	assert.Equal(t, [][]byte{
+		{0xff, 0xd8, 1, 2, 3, 4, 0xff, 0xd9},
+		{5, 6, 7, 8, 0xff, 0xd9},
+	}, frames)
+
+	// One fragment per frame.
+	frames = readEncapsulatedFrames(t, nil, fragments,
+		dicom.MustNewElement(dicomtag.NumberOfFrames, "5"))
+	assert.Equal(t, fragments, frames)
+
+	// When the frames can't be found, each fragment is treated as a frame.
+	frames = readEncapsulatedFrames(t, nil, fragments,
+		dicom.MustNewElement(dicomtag.NumberOfFrames, "3"))
+	assert.Equal(t, fragments, frames)
+}
+
+func TestWriteFramesWithBasicOffsetTable(t *testing.T) {
+	fragments := [][]byte{{1, 2}, {3, 4}, {5, 6, 7, 8}}
+	ds, err := dicom.ReadDataSetInBytes(writeEncapsulated(t, []uint32{0, 20}, fragments), dicom.ReadOptions{})
+	require.NoError(t, err)
+	// The offsets are recomputed for one fragment per frame.
+	image := readPixelDataInfo(t, rewrite(t, ds))
+	assert.Equal(t, []uint32{0, 12}, image.Offsets)
+	assert.Equal(t, [][]byte{{1, 2, 3, 4}, {5, 6, 7, 8}}, image.Frames)
+
+	// Frames found without an offset table get one.
+	jpeg := func(b ...byte) []byte { return append([]byte{0xff, 0xd8}, b...) }
+	ds, err = dicom.ReadDataSetInBytes(writeEncapsulated(t, nil, [][]byte{jpeg(1, 2), {3, 4}, jpeg(5, 6)},
+		dicom.MustNewElement(dicomtag.NumberOfFrames, "2")), dicom.ReadOptions{})
+	require.NoError(t, err)
+	ds.Remove(dicomtag.NumberOfFrames)
+	image = readPixelDataInfo(t, rewrite(t, ds))
+	assert.Equal(t, []uint32{0, 14}, image.Offsets)
+	assert.Equal(t, [][]byte{jpeg(1, 2, 3, 4), jpeg(5, 6)}, image.Frames)
+}
+
+func TestWriteFramesWithExtendedOffsetTable(t *testing.T) {
+	fragments := [][]byte{{1, 2}, {3, 4}, {5, 6}, {7, 8}}
+	ds, err := dicom.ReadDataSetInBytes(writeEncapsulated(t, nil, fragments,
+		dicom.MustNewElement(dicomtag.NumberOfFrames, "2"),
+		dicom.MustNewElement(dicomtag.ExtendedOffsetTable, uint64(0), uint64(30)),
+		dicom.MustNewElement(dicomtag.ExtendedOffsetTableLengths, uint64(6), uint64(2))), dicom.ReadOptions{})
+	require.NoError(t, err)
+	ds2 := rewrite(t, ds)
+	image := readPixelDataInfo(t, ds2)
+	// The basic offset table must be empty when the extended one exists.
+	assert.Empty(t, image.Offsets)
+	assert.Equal(t, [][]byte{{1, 2, 3, 4, 5, 6}, {7, 8}}, image.Frames)
+	elem, err := ds2.FindElementByTag(dicomtag.ExtendedOffsetTable)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{uint64(0), uint64(14)}, elem.Value)
+	elem, err = ds2.FindElementByTag(dicomtag.ExtendedOffsetTableLengths)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{uint64(6), uint64(2)}, elem.Value)
+
+	// The table is dropped with native pixel data.
+	ds.Set(&dicom.Element{
+		Tag:   dicomtag.PixelData,
+		VR:    "OW",
+		Value: []interface{}{dicom.PixelDataInfo{Frames: [][]byte{{1, 2}}}},
+	})
+	ds.Set(dicom.MustNewElement(dicomtag.TransferSyntaxUID, "1.2.840.10008.1.2.1"))
+	ds2 = rewrite(t, ds)
+	assert.False(t, ds2.Has(dicomtag.ExtendedOffsetTable))
+	assert.False(t, ds2.Has(dicomtag.ExtendedOffsetTableLengths))
+}
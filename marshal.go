@@ -0,0 +1,520 @@
+package dicom
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/msz-kp/go-dicom/dicomtag"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	personNameType = reflect.TypeOf(PersonName{})
+	tagType        = reflect.TypeOf(dicomtag.Tag{})
+	elementPtrType = reflect.TypeOf(&Element{})
+	bytesType      = reflect.TypeOf([]byte{})
+)
+
+type structField struct {
+	index     int
+	tag       dicomtag.Tag
+	omitEmpty bool
+}
+
+// parseStructTag parses a "dicom" struct tag. It returns false if the field
+// should be ignored.
+func parseStructTag(s string) (structField, bool, error) {
+	var f structField
+	if s == "" || s == "-" {
+		return f, false, nil
+	}
+	parts := strings.Split(s, ",")
+	name, opts := parts[0], parts[1:]
+	if len(parts) >= 2 && isHex16(parts[0]) && isHex16(parts[1]) {
+		name, opts = parts[0]+","+parts[1], parts[2:]
+	}
+	tag, err := dicomtag.ParseTag(name)
+	if err != nil {
+		return f, false, err
+	}
+	f.tag = tag
+	for _, opt := range opts {
+		switch opt {
+		case "omitempty":
+			f.omitEmpty = true
+		default:
+			return f, false, fmt.Errorf("unknown option '%s' in struct tag '%s'", opt, s)
+		}
+	}
+	return f, true, nil
+}
+
+// isHex16 checks if "s" looks like a group or element number, e.g., "0010" or
+// "(0010".
+func isHex16(s string) bool {
+	s = strings.Trim(s, "() ")
+	if len(s) != 4 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte("0123456789abcdefABCDEF", s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func structFields(t reflect.Type) ([]structField, error) {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		f, ok, err := parseStructTag(sf.Tag.Get("dicom"))
+		if err != nil {
+			return nil, fmt.Errorf("field %s.%s: %v", t.Name(), sf.Name, err)
+		}
+		if !ok {
+			continue
+		}
+		if sf.PkgPath != "" {
+			return nil, fmt.Errorf("field %s.%s is unexported", t.Name(), sf.Name)
+		}
+		f.index = i
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// Unmarshal stores the elements of "ds" into the struct pointed to by "v".
+// The fields to fill carry a "dicom" struct tag. The tag names the element,
+// either by its name in the dictionary or by its "gggg,eeee" hex form,
+// optionally followed by ",omitempty". Fields without the tag, or with tag
+// "-", are ignored.
+//
+//	type Patient struct {
+//		Name      dicom.PersonName `dicom:"PatientName"`
+//		ID        string           `dicom:"0010,0020"`
+//		BirthDate time.Time        `dicom:"PatientBirthDate,omitempty"`
+//		Weight    float64          `dicom:"PatientWeight,omitempty"`
+//		Studies   []Study          `dicom:"ReferencedStudySequence,omitempty"`
+//	}
+//
+// A field may be of the following types. A slice of any of them, except
+// []byte, stores all the values of a multi-valued element.
+//
+//   - string, for any string VR.
+//   - int*, uint*, float*, for DS and IS as well as the binary numeric VRs.
+//   - time.Time, for DA, TM and DT. When unmarshaling, DA and TM values, and DT
+//     values without a UTC offset are taken to be in the zone given by the
+//     TimezoneOffsetFromUTC element, or UTC if the element is absent. TM
+//     values fall on 0000-01-01.
+//   - PersonName, for PN.
+//   - dicomtag.Tag, for AT.
+//   - []byte, for OB, OW and UN.
+//   - a struct, or a slice of structs, for SQ. Each struct is an item.
+//   - *Element, which stores the element as is.
+//   - a pointer to any of the above, which is left nil if the element is
+//     absent.
+//
+// Fields whose element is absent in "ds" are left untouched. It returns an
+// error if an element can't be converted to the type of its field.
+func Unmarshal(ds *DataSet, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("dicom.Unmarshal: expect a non-nil pointer to a struct, but found %T", v)
+	}
+	loc, err := ds.TimezoneOffsetFromUTC()
+	if err != nil {
+		loc = time.UTC
+	}
+	if err := unmarshalElements(ds.Elements, rv.Elem(), loc); err != nil {
+		return fmt.Errorf("dicom.Unmarshal: %v", err)
+	}
+	return nil
+}
+
+func unmarshalElements(elems []*Element, rv reflect.Value, loc *time.Location) error {
+	fields, err := structFields(rv.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		elem, err := FindElementByTag(elems, f.tag)
+		if err != nil {
+			continue
+		}
+		if err := unmarshalValue(elem, rv.Field(f.index), loc); err != nil {
+			return fmt.Errorf("field %s.%s: %v: %v",
+				rv.Type().Name(), rv.Type().Field(f.index).Name, dicomtag.DebugString(f.tag), err)
+		}
+	}
+	return nil
+}
+
+func unmarshalValue(elem *Element, fv reflect.Value, loc *time.Location) error {
+	switch fv.Type() {
+	case elementPtrType:
+		fv.Set(reflect.ValueOf(elem))
+		return nil
+	case bytesType:
+		if len(elem.Value) != 1 {
+			return fmt.Errorf("found %d values, expect 1", len(elem.Value))
+		}
+		b, ok := elem.Value[0].([]byte)
+		if !ok {
+			return fmt.Errorf("[]byte value not found in %v", elem)
+		}
+		fv.SetBytes(b)
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.Ptr:
+		p := reflect.New(fv.Type().Elem())
+		if err := unmarshalValue(elem, p.Elem(), loc); err != nil {
+			return err
+		}
+		fv.Set(p)
+		return nil
+	case reflect.Slice:
+		values := reflect.MakeSlice(fv.Type(), len(elem.Value), len(elem.Value))
+		for i, v := range elem.Value {
+			// Convert each value as if it were a single-valued element.
+			single := &Element{Tag: elem.Tag, VR: elem.VR, Value: []interface{}{v}}
+			if err := unmarshalValue(single, values.Index(i), loc); err != nil {
+				return err
+			}
+		}
+		fv.Set(values)
+		return nil
+	}
+
+	if len(elem.Value) != 1 {
+		return fmt.Errorf("found %d values, expect 1", len(elem.Value))
+	}
+	switch fv.Type() {
+	case timeType:
+		t, err := parseTimeValue(elem, loc)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	case personNameType:
+		name, err := elem.GetPersonName()
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(name))
+		return nil
+	case tagType:
+		tag, ok := elem.Value[0].(dicomtag.Tag)
+		if !ok {
+			return fmt.Errorf("tag value not found in %v", elem)
+		}
+		fv.Set(reflect.ValueOf(tag))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.Struct:
+		item, ok := elem.Value[0].(*Element)
+		if !ok || item.Tag != dicomtag.Item {
+			return fmt.Errorf("item not found in %v", elem)
+		}
+		return unmarshalElements(item.GetElements(), fv, loc)
+	case reflect.String:
+		s, err := elem.GetString()
+		if err != nil {
+			return err
+		}
+		fv.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := elem.GetInt64()
+		if err != nil {
+			return err
+		}
+		if fv.OverflowInt(i) {
+			return fmt.Errorf("%d overflows %v", i, fv.Type())
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u, ok := elem.Value[0].(uint64); ok {
+			if fv.OverflowUint(u) {
+				return fmt.Errorf("%d overflows %v", u, fv.Type())
+			}
+			fv.SetUint(u)
+			return nil
+		}
+		i, err := elem.GetInt64()
+		if err != nil {
+			return err
+		}
+		if i < 0 || fv.OverflowUint(uint64(i)) {
+			return fmt.Errorf("%d overflows %v", i, fv.Type())
+		}
+		fv.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		f, err := elem.GetFloat64()
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %v", fv.Type())
+	}
+	return nil
+}
+
+// parseTimeValue converts the DA, TM or DT value stored in "elem".
+func parseTimeValue(elem *Element, loc *time.Location) (time.Time, error) {
+	s, err := elem.GetString()
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch elem.VR {
+	case "DA":
+		d, _, err := ParseDate(s)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return d.Time(loc)
+	case "TM":
+		t, _, err := ParseTime(s)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return t.Time(DateInfo{Year: 0, Month: 1, Day: 1}, loc)
+	case "DT":
+		dt, _, err := ParseDateTime(s)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return dt.Time(loc)
+	}
+	return time.Time{}, fmt.Errorf("can't convert VR %s to time.Time", elem.VR)
+}
+
+// Marshal converts the struct "v", or a pointer to it, to a DataSet. It's the
+// inverse of Unmarshal; see Unmarshal for the mapping. Fields of nil pointer type, and fields with
+// the omitempty option whose value is the zero value or an empty slice, are
+// skipped. The elements are sorted by tag. The tags must be in the dictionary,
+// since it defines the VR of the elements.
+//
+// Marshal doesn't add the meta elements needed by WriteDataSet.
+func Marshal(v interface{}) (*DataSet, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("dicom.Marshal: expect a struct, but found %T", v)
+	}
+	elems, err := marshalElements(rv)
+	if err != nil {
+		return nil, fmt.Errorf("dicom.Marshal: %v", err)
+	}
+	ds := &DataSet{Elements: elems}
+	ds.Reindex()
+	return ds, nil
+}
+
+func marshalElements(rv reflect.Value) ([]*Element, error) {
+	fields, err := structFields(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	var elems []*Element
+	for _, f := range fields {
+		fv := rv.Field(f.index)
+		if (fv.Kind() == reflect.Ptr && fv.IsNil()) || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		elem, err := marshalValue(f.tag, fv)
+		if err != nil {
+			return nil, fmt.Errorf("field %s.%s: %v: %v",
+				rv.Type().Name(), rv.Type().Field(f.index).Name, dicomtag.DebugString(f.tag), err)
+		}
+		elems = append(elems, elem)
+	}
+	sort.SliceStable(elems, func(i, j int) bool {
+		return elems[i].Tag.Compare(elems[j].Tag) < 0
+	})
+	return elems, nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Ptr:
+		return v.IsNil()
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+func marshalValue(tag dicomtag.Tag, fv reflect.Value) (*Element, error) {
+	if fv.Type() == elementPtrType {
+		return fv.Interface().(*Element), nil
+	}
+	if fv.Kind() == reflect.Ptr {
+		return marshalValue(tag, fv.Elem())
+	}
+	ti, err := dicomtag.Find(tag)
+	if err != nil {
+		return nil, err
+	}
+	kind := dicomtag.GetVRKind(tag, ti.VR)
+	if fv.Type() == bytesType {
+		if kind != dicomtag.VRBytes {
+			return nil, fmt.Errorf("can't store []byte in VR %s", ti.VR)
+		}
+		return NewElement(tag, fv.Bytes())
+	}
+	if kind == dicomtag.VRSequence {
+		return marshalSequence(tag, fv)
+	}
+	var values []interface{}
+	if fv.Kind() == reflect.Slice {
+		for i := 0; i < fv.Len(); i++ {
+			v, err := marshalScalar(ti.VR, kind, fv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+	} else {
+		v, err := marshalScalar(ti.VR, kind, fv)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return NewElement(tag, values...)
+}
+
+func marshalSequence(tag dicomtag.Tag, fv reflect.Value) (*Element, error) {
+	var structs []reflect.Value
+	switch {
+	case fv.Kind() == reflect.Struct:
+		structs = append(structs, fv)
+	case fv.Kind() == reflect.Slice:
+		for i := 0; i < fv.Len(); i++ {
+			v := fv.Index(i)
+			for v.Kind() == reflect.Ptr && !v.IsNil() {
+				v = v.Elem()
+			}
+			if v.Kind() != reflect.Struct {
+				return nil, fmt.Errorf("can't store %v in a sequence", fv.Type())
+			}
+			structs = append(structs, v)
+		}
+	default:
+		return nil, fmt.Errorf("can't store %v in a sequence", fv.Type())
+	}
+	items := make([]interface{}, len(structs))
+	for i, v := range structs {
+		subelems, err := marshalElements(v)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, len(subelems))
+		for j, subelem := range subelems {
+			values[j] = subelem
+		}
+		items[i] = MustNewElement(dicomtag.Item, values...)
+	}
+	return NewElement(tag, items...)
+}
+
+// marshalScalar converts a field value into an Element value of VR "vr".
+func marshalScalar(vr string, kind dicomtag.VRKind, v reflect.Value) (interface{}, error) {
+	switch v.Type() {
+	case timeType:
+		t := v.Interface().(time.Time)
+		switch vr {
+		case "DA":
+			return FormatDate(t), nil
+		case "TM":
+			return FormatTime(t), nil
+		case "DT":
+			return FormatDateTime(t), nil
+		}
+		return nil, fmt.Errorf("can't store time.Time in VR %s", vr)
+	case personNameType:
+		if vr != "PN" {
+			return nil, fmt.Errorf("can't store PersonName in VR %s", vr)
+		}
+		return v.Interface().(PersonName).String(), nil
+	case tagType:
+		if kind != dicomtag.VRTagList {
+			return nil, fmt.Errorf("can't store dicomtag.Tag in VR %s", vr)
+		}
+		return v.Interface(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if kind != dicomtag.VRStringList && kind != dicomtag.VRDate && kind != dicomtag.VRString {
+			return nil, fmt.Errorf("can't store string in VR %s", vr)
+		}
+		return v.String(), nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		switch {
+		case vr == "DS":
+			return FormatDS(f)
+		case kind == dicomtag.VRFloat32List:
+			return float32(f), nil
+		case kind == dicomtag.VRFloat64List:
+			return f, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := v.Uint()
+		if kind == dicomtag.VRUInt64List {
+			return u, nil
+		}
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows VR %s", u, vr)
+		}
+		return marshalInt(vr, kind, int64(u))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return marshalInt(vr, kind, v.Int())
+	}
+	return nil, fmt.Errorf("can't store %v in VR %s", v.Type(), vr)
+}
+
+func marshalInt(vr string, kind dicomtag.VRKind, i int64) (interface{}, error) {
+	inRange := func(min, max int64) bool { return i >= min && i <= max }
+	var v interface{}
+	ok := true
+	switch {
+	case vr == "IS":
+		return FormatIS(i)
+	case vr == "DS":
+		return FormatDS(float64(i))
+	case kind == dicomtag.VRUInt16List:
+		v, ok = uint16(i), inRange(0, math.MaxUint16)
+	case kind == dicomtag.VRUInt32List:
+		v, ok = uint32(i), inRange(0, math.MaxUint32)
+	case kind == dicomtag.VRUInt64List:
+		v, ok = uint64(i), i >= 0
+	case kind == dicomtag.VRInt16List:
+		v, ok = int16(i), inRange(math.MinInt16, math.MaxInt16)
+	case kind == dicomtag.VRInt32List:
+		v, ok = int32(i), inRange(math.MinInt32, math.MaxInt32)
+	case kind == dicomtag.VRInt64List:
+		v = i
+	case kind == dicomtag.VRFloat32List:
+		v = float32(i)
+	case kind == dicomtag.VRFloat64List:
+		v = float64(i)
+	default:
+		return nil, fmt.Errorf("can't store an integer in VR %s", vr)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%d overflows VR %s", i, vr)
+	}
+	return v, nil
+}
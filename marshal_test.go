@@ -0,0 +1,186 @@
+package dicom_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/msz-kp/go-dicom"
+	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/msz-kp/go-dicom/dicomuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testImageRef struct {
+	SOPClassUID    string `dicom:"ReferencedSOPClassUID"`
+	SOPInstanceUID string `dicom:"ReferencedSOPInstanceUID"`
+}
+
+type testImage struct {
+	PatientName      dicom.PersonName `dicom:"PatientName"`
+	PatientID        string           `dicom:"0010,0020"`
+	PatientWeight    float64          `dicom:"PatientWeight,omitempty"`
+	StudyDate        time.Time        `dicom:"StudyDate"`
+	StudyTime        time.Time        `dicom:"StudyTime"`
+	AcquisitionTime  *time.Time       `dicom:"AcquisitionDateTime"`
+	InstanceNumber   int              `dicom:"InstanceNumber"`
+	Rows             uint16           `dicom:"Rows"`
+	Columns          int              `dicom:"(0028,0011)"`
+	PixelSpacing     []float64        `dicom:"PixelSpacing"`
+	ImageType        []string         `dicom:"ImageType"`
+	FrameIncrement   []dicomtag.Tag   `dicom:"FrameIncrementPointer,omitempty"`
+	ReferencedImages []testImageRef   `dicom:"ReferencedImageSequence"`
+	Modality         *dicom.Element   `dicom:"Modality"`
+	Ignored          string
+	Skipped          string `dicom:"-"`
+}
+
+func TestMarshal(t *testing.T) {
+	acquired := time.Date(2017, 9, 27, 10, 30, 15, 250000000, time.FixedZone("", 9*3600))
+	v := testImage{
+		PatientName:     dicom.PersonName{Alphabetic: dicom.PersonNameGroup{FamilyName: "Doe", GivenName: "John"}},
+		PatientID:       "12345",
+		StudyDate:       time.Date(2017, 9, 27, 0, 0, 0, 0, time.UTC),
+		StudyTime:       time.Date(0, 1, 1, 10, 30, 15, 0, time.UTC),
+		AcquisitionTime: &acquired,
+		InstanceNumber:  7,
+		Rows:            512,
+		Columns:         256,
+		PixelSpacing:    []float64{0.5, 0.25},
+		ImageType:       []string{"ORIGINAL", "PRIMARY"},
+		ReferencedImages: []testImageRef{
+			{"1.2.840.10008.5.1.4.1.1.2", "1.2.3.1"},
+			{"1.2.840.10008.5.1.4.1.1.2", "1.2.3.2"},
+		},
+		Modality: dicom.MustNewElement(dicomtag.Modality, "CT"),
+		Ignored:  "foo",
+		Skipped:  "bar",
+	}
+	ds, err := dicom.Marshal(&v)
+	require.NoError(t, err)
+
+	expected := map[dicomtag.Tag][]interface{}{
+		dicomtag.PatientName:         {"Doe^John"},
+		dicomtag.PatientID:           {"12345"},
+		dicomtag.StudyDate:           {"20170927"},
+		dicomtag.StudyTime:           {"103015"},
+		dicomtag.AcquisitionDateTime: {"20170927103015.25+0900"},
+		dicomtag.InstanceNumber:      {"7"},
+		dicomtag.Rows:                {uint16(512)},
+		dicomtag.Columns:             {uint16(256)},
+		dicomtag.PixelSpacing:        {"0.5", "0.25"},
+		dicomtag.ImageType:           {"ORIGINAL", "PRIMARY"},
+		dicomtag.Modality:            {"CT"},
+	}
+	// ReferencedImageSequence is checked below.
+	require.Len(t, ds.Elements, len(expected)+1)
+	for i, elem := range ds.Elements {
+		if i > 0 {
+			assert.True(t, ds.Elements[i-1].Tag.Compare(elem.Tag) < 0, "elements must be sorted")
+		}
+		if elem.Tag == dicomtag.ReferencedImageSequence {
+			require.Len(t, elem.Value, 2)
+			item := elem.Value[1].(*dicom.Element)
+			sub, err := dicom.FindElementByTag(item.GetElements(), dicomtag.ReferencedSOPInstanceUID)
+			require.NoError(t, err)
+			assert.Equal(t, "1.2.3.2", sub.MustGetString())
+			continue
+		}
+		assert.Equal(t, expected[elem.Tag], elem.Value, dicomtag.DebugString(elem.Tag))
+	}
+
+	// Round trip through the writer and the reader.
+	ds.Elements = append([]*dicom.Element{
+		dicom.MustNewElement(dicomtag.TransferSyntaxUID, dicomuid.ExplicitVRLittleEndian),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPClassUID, "1.2.840.10008.5.1.4.1.1.2"),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPInstanceUID, "1.2.3.4.5.6.7"),
+	}, ds.Elements...)
+	var buf bytes.Buffer
+	require.NoError(t, dicom.WriteDataSet(&buf, ds))
+	ds, err = dicom.ReadDataSet(&buf, dicom.ReadOptions{})
+	require.NoError(t, err)
+
+	var v2 testImage
+	require.NoError(t, dicom.Unmarshal(ds, &v2))
+	assert.Equal(t, v.PatientName, v2.PatientName)
+	assert.Equal(t, v.PatientID, v2.PatientID)
+	assert.True(t, v.StudyDate.Equal(v2.StudyDate), v2.StudyDate)
+	assert.True(t, v.StudyTime.Equal(v2.StudyTime), v2.StudyTime)
+	require.NotNil(t, v2.AcquisitionTime)
+	assert.True(t, acquired.Equal(*v2.AcquisitionTime), v2.AcquisitionTime)
+	assert.Equal(t, v.InstanceNumber, v2.InstanceNumber)
+	assert.Equal(t, v.Rows, v2.Rows)
+	assert.Equal(t, v.Columns, v2.Columns)
+	assert.Equal(t, v.PixelSpacing, v2.PixelSpacing)
+	assert.Equal(t, v.ImageType, v2.ImageType)
+	assert.Equal(t, v.ReferencedImages, v2.ReferencedImages)
+	assert.Equal(t, "CT", v2.Modality.MustGetString())
+	assert.Equal(t, "", v2.Ignored)
+	assert.Equal(t, "", v2.Skipped)
+}
+
+func TestUnmarshalFile(t *testing.T) {
+	ds := mustReadFile(t, "examples/IM-0001-0001.dcm", dicom.ReadOptions{DropPixelData: true})
+	var v struct {
+		PatientName     dicom.PersonName `dicom:"PatientName"`
+		Rows            int              `dicom:"Rows"`
+		SliceThickness  *float64         `dicom:"SliceThickness"`
+		NoSuchElement   *string          `dicom:"PerformedProcedureStepID"`
+		SOPInstanceUIDs []string         `dicom:"SOPInstanceUID"`
+	}
+	require.NoError(t, dicom.Unmarshal(ds, &v))
+	elem, err := ds.FindElementByTag(dicomtag.PatientName)
+	require.NoError(t, err)
+	assert.Equal(t, elem.MustGetString(), v.PatientName.String())
+	elem, err = ds.FindElementByTag(dicomtag.Rows)
+	require.NoError(t, err)
+	assert.Equal(t, int(elem.MustGetUInt16()), v.Rows)
+	require.NotNil(t, v.SliceThickness)
+	assert.Nil(t, v.NoSuchElement)
+	assert.Len(t, v.SOPInstanceUIDs, 1)
+}
+
+func TestUnmarshalTimezone(t *testing.T) {
+	ds := &dicom.DataSet{Elements: []*dicom.Element{
+		dicom.MustNewElement(dicomtag.TimezoneOffsetFromUTC, "-0500"),
+		dicom.MustNewElement(dicomtag.StudyDate, "20170927"),
+	}}
+	var v struct {
+		StudyDate time.Time `dicom:"StudyDate"`
+	}
+	require.NoError(t, dicom.Unmarshal(ds, &v))
+	assert.True(t, v.StudyDate.Equal(time.Date(2017, 9, 27, 5, 0, 0, 0, time.UTC)), v.StudyDate)
+}
+
+func TestMarshalErrors(t *testing.T) {
+	_, err := dicom.Marshal(struct {
+		Rows int `dicom:"Rows"`
+	}{Rows: 70000})
+	assert.Error(t, err)
+	_, err = dicom.Marshal(struct {
+		Rows string `dicom:"Rows"`
+	}{Rows: "512"})
+	assert.Error(t, err)
+	_, err = dicom.Marshal(struct {
+		Name string `dicom:"NoSuchElement"`
+	}{})
+	assert.Error(t, err)
+	_, err = dicom.Marshal(struct {
+		Name string `dicom:"PatientName,bogus"`
+	}{})
+	assert.Error(t, err)
+	_, err = dicom.Marshal("foo")
+	assert.Error(t, err)
+
+	ds := &dicom.DataSet{Elements: []*dicom.Element{
+		dicom.MustNewElement(dicomtag.InstanceNumber, "foo"),
+	}}
+	var v struct {
+		InstanceNumber int `dicom:"InstanceNumber"`
+	}
+	err = dicom.Unmarshal(ds, &v)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "InstanceNumber")
+	assert.Error(t, dicom.Unmarshal(ds, v))
+}
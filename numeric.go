@@ -0,0 +1,266 @@
+package dicom
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/msz-kp/go-dicom/dicomtag"
+)
+
+// Max length of a DS and an IS value, in bytes. P3.5 6.2.
+const (
+	maxDSLength = 16
+	maxISLength = 12
+)
+
+// ParseDS parses a decimal string (VR DS). Leading and trailing spaces are
+// ignored. It returns an error if the string contains characters other than
+// those allowed in a DS, i.e., "0-9+-Ee.", or if it is longer than 16 bytes.
+func ParseDS(s string) (float64, error) {
+	s = strings.Trim(s, " ")
+	if s == "" {
+		return 0, fmt.Errorf("dicom.ParseDS: empty string")
+	}
+	if len(s) > maxDSLength {
+		return 0, fmt.Errorf("dicom.ParseDS: '%s' is longer than %d bytes", s, maxDSLength)
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte("0123456789+-Ee.", s[i]) < 0 {
+			return 0, fmt.Errorf("dicom.ParseDS: invalid character '%c' in '%s'", s[i], s)
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("dicom.ParseDS: %v", err)
+	}
+	return v, nil
+}
+
+// ParseIS parses an integer string (VR IS). Leading and trailing spaces are
+// ignored. It returns an error if the string isn't a decimal integer, or if
+// the value doesn't fit in the range [-2^31, 2^31-1].
+func ParseIS(s string) (int64, error) {
+	s = strings.Trim(s, " ")
+	if s == "" {
+		return 0, fmt.Errorf("dicom.ParseIS: empty string")
+	}
+	if len(s) > maxISLength {
+		return 0, fmt.Errorf("dicom.ParseIS: '%s' is longer than %d bytes", s, maxISLength)
+	}
+	v, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("dicom.ParseIS: %v", err)
+	}
+	return v, nil
+}
+
+// FormatDS formats "v" as a decimal string (VR DS). It uses the shortest
+// representation that round-trips, and drops the least significant digits if
+// the result would exceed 16 bytes. It returns an error for NaN and infinity,
+// which DS can't represent.
+func FormatDS(v float64) (string, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "", fmt.Errorf("dicom.FormatDS: %v can't be represented in DS", v)
+	}
+	s := strconv.FormatFloat(v, 'g', -1, 64)
+	for prec := maxDSLength; len(s) > maxDSLength; prec-- {
+		t := strconv.FormatFloat(v, 'g', prec, 64)
+		if _, err := strconv.ParseFloat(t, 64); err != nil {
+			// Rounded up beyond math.MaxFloat64. Try fewer digits.
+			continue
+		}
+		s = t
+	}
+	return s, nil
+}
+
+// FormatIS formats "v" as an integer string (VR IS). It returns an error if
+// the value doesn't fit in the range [-2^31, 2^31-1].
+func FormatIS(v int64) (string, error) {
+	if v < math.MinInt32 || v > math.MaxInt32 {
+		return "", fmt.Errorf("dicom.FormatIS: %d is out of range", v)
+	}
+	return strconv.FormatInt(v, 10), nil
+}
+
+// NewDSElement creates a new element of VR DS, such as PixelSpacing, with the
+// given values formatted by FormatDS.
+func NewDSElement(tag dicomtag.Tag, values ...float64) (*Element, error) {
+	if err := checkDictionaryVR(tag, "DS"); err != nil {
+		return nil, err
+	}
+	strs := make([]interface{}, len(values))
+	for i, v := range values {
+		s, err := FormatDS(v)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", dicomtag.DebugString(tag), err)
+		}
+		strs[i] = s
+	}
+	return NewElement(tag, strs...)
+}
+
+// NewISElement creates a new element of VR IS, such as InstanceNumber, with the
+// given values formatted by FormatIS.
+func NewISElement(tag dicomtag.Tag, values ...int64) (*Element, error) {
+	if err := checkDictionaryVR(tag, "IS"); err != nil {
+		return nil, err
+	}
+	strs := make([]interface{}, len(values))
+	for i, v := range values {
+		s, err := FormatIS(v)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", dicomtag.DebugString(tag), err)
+		}
+		strs[i] = s
+	}
+	return NewElement(tag, strs...)
+}
+
+func checkDictionaryVR(tag dicomtag.Tag, vr string) error {
+	ti, err := dicomtag.Find(tag)
+	if err != nil {
+		return err
+	}
+	if ti.VR != vr {
+		return fmt.Errorf("%v: VR is %s, not %s", dicomtag.DebugString(tag), ti.VR, vr)
+	}
+	return nil
+}
+
+// GetFloat64s returns the values stored in the element as float64s. String
+// values, as found in a DS or IS element, are parsed by ParseDS. Values of
+// VR FL and FD, and of integer VRs, such as US and SL, are converted as well.
+// It returns an error if a value is not a number.
+func (e *Element) GetFloat64s() ([]float64, error) {
+	values := make([]float64, 0, len(e.Value))
+	for _, v := range e.Value {
+		switch v := v.(type) {
+		case string:
+			f, err := ParseDS(v)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", dicomtag.DebugString(e.Tag), err)
+			}
+			values = append(values, f)
+		case float64:
+			values = append(values, v)
+		case float32:
+			values = append(values, float64(v))
+		case int64:
+			values = append(values, float64(v))
+		case int32:
+			values = append(values, float64(v))
+		case int16:
+			values = append(values, float64(v))
+		case uint64:
+			values = append(values, float64(v))
+		case uint32:
+			values = append(values, float64(v))
+		case uint16:
+			values = append(values, float64(v))
+		default:
+			return nil, fmt.Errorf("float64 value not found in %v", e.String())
+		}
+	}
+	return values, nil
+}
+
+// MustGetFloat64s is similar to GetFloat64s, but crashes the process on error.
+func (e *Element) MustGetFloat64s() []float64 {
+	values, err := e.GetFloat64s()
+	if err != nil {
+		panic(err)
+	}
+	return values
+}
+
+// GetFloat64 is similar to GetFloat64s, but it returns an error if the element
+// contains zero or >1 values.
+func (e *Element) GetFloat64() (float64, error) {
+	if len(e.Value) != 1 {
+		return 0, fmt.Errorf("Found %d value(s) in getfloat64 (expect 1): %v", len(e.Value), e)
+	}
+	values, err := e.GetFloat64s()
+	if err != nil {
+		return 0, err
+	}
+	return values[0], nil
+}
+
+// MustGetFloat64 is similar to GetFloat64, but panics on error.
+func (e *Element) MustGetFloat64() float64 {
+	v, err := e.GetFloat64()
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// GetInt64s returns the values stored in the element as int64s. String
+// values, as found in an IS element, are parsed by ParseIS. Values of integer
+// VRs, such as US and SL, are converted as well. It returns an error if a
+// value is not an integer.
+func (e *Element) GetInt64s() ([]int64, error) {
+	values := make([]int64, 0, len(e.Value))
+	for _, v := range e.Value {
+		switch v := v.(type) {
+		case string:
+			i, err := ParseIS(v)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", dicomtag.DebugString(e.Tag), err)
+			}
+			values = append(values, i)
+		case int64:
+			values = append(values, v)
+		case int32:
+			values = append(values, int64(v))
+		case int16:
+			values = append(values, int64(v))
+		case uint32:
+			values = append(values, int64(v))
+		case uint16:
+			values = append(values, int64(v))
+		case uint64:
+			if v > math.MaxInt64 {
+				return nil, fmt.Errorf("%v: %d overflows int64", dicomtag.DebugString(e.Tag), v)
+			}
+			values = append(values, int64(v))
+		default:
+			return nil, fmt.Errorf("int64 value not found in %v", e.String())
+		}
+	}
+	return values, nil
+}
+
+// MustGetInt64s is similar to GetInt64s, but crashes the process on error.
+func (e *Element) MustGetInt64s() []int64 {
+	values, err := e.GetInt64s()
+	if err != nil {
+		panic(err)
+	}
+	return values
+}
+
+// GetInt64 is similar to GetInt64s, but it returns an error if the element
+// contains zero or >1 values.
+func (e *Element) GetInt64() (int64, error) {
+	if len(e.Value) != 1 {
+		return 0, fmt.Errorf("Found %d value(s) in getint64 (expect 1): %v", len(e.Value), e)
+	}
+	values, err := e.GetInt64s()
+	if err != nil {
+		return 0, err
+	}
+	return values[0], nil
+}
+
+// MustGetInt64 is similar to GetInt64, but panics on error.
+func (e *Element) MustGetInt64() int64 {
+	v, err := e.GetInt64()
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
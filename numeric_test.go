@@ -0,0 +1,112 @@
+package dicom_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	dicom "github.com/msz-kp/go-dicom"
+	"github.com/msz-kp/go-dicom/dicomtag"
+)
+
+func TestParseDS(t *testing.T) {
+	for s, expected := range map[string]float64{
+		"1":                1,
+		" -1.5 ":           -1.5,
+		"+.5":              0.5,
+		"5.":               5,
+		"1.2E-3":           0.0012,
+		"6.02e23":          6.02e23,
+		"0.12345678901234": 0.12345678901234,
+	} {
+		v, err := dicom.ParseDS(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, v, s)
+	}
+	for _, s := range []string{"", "  ", "1,5", "NaN", "Inf", "0x10", "1_000", "0.123456789012345", "1.2.3"} {
+		_, err := dicom.ParseDS(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParseIS(t *testing.T) {
+	for s, expected := range map[string]int64{
+		"0":            0,
+		" +12 ":        12,
+		"-2147483648":  math.MinInt32,
+		"2147483647 ":  math.MaxInt32,
+		"000000000042": 42,
+	} {
+		v, err := dicom.ParseIS(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, v, s)
+	}
+	for _, s := range []string{"", "1.0", "1e3", "2147483648", "0x10", "0000000000042"} {
+		_, err := dicom.ParseIS(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestFormatDS(t *testing.T) {
+	for _, v := range []float64{1, -1.5, 0.1, 1.0 / 3, math.Pi * 1e10, -math.Pi * 1e-300, math.MaxFloat64, math.SmallestNonzeroFloat64} {
+		s, err := dicom.FormatDS(v)
+		require.NoError(t, err)
+		assert.True(t, len(s) <= 16, s)
+		v2, err := dicom.ParseDS(s)
+		require.NoError(t, err, s)
+		assert.InDelta(t, 1, v2/v, 1e-8, s)
+	}
+	s, err := dicom.FormatDS(0.5)
+	require.NoError(t, err)
+	assert.Equal(t, "0.5", s)
+	s, err = dicom.FormatDS(0)
+	require.NoError(t, err)
+	assert.Equal(t, "0", s)
+	_, err = dicom.FormatDS(math.NaN())
+	assert.Error(t, err)
+	_, err = dicom.FormatDS(math.Inf(-1))
+	assert.Error(t, err)
+
+	_, err = dicom.FormatIS(math.MaxInt32 + 1)
+	assert.Error(t, err)
+}
+
+func TestNumericElement(t *testing.T) {
+	elem, err := dicom.NewDSElement(dicomtag.PixelSpacing, 0.5, 1.0/3)
+	require.NoError(t, err)
+	assert.Equal(t, "DS", elem.VR)
+	assert.Equal(t, []interface{}{"0.5", "0.33333333333333"}, elem.Value)
+	values, err := elem.GetFloat64s()
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0.5, 0.33333333333333}, values)
+	_, err = elem.GetFloat64()
+	assert.Error(t, err)
+
+	elem, err = dicom.NewISElement(dicomtag.InstanceNumber, 12)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), elem.MustGetInt64())
+	assert.Equal(t, float64(12), elem.MustGetFloat64())
+
+	_, err = dicom.NewDSElement(dicomtag.InstanceNumber, 1)
+	assert.Error(t, err)
+
+	assert.Equal(t, int64(512), dicom.MustNewElement(dicomtag.Rows, uint16(512)).MustGetInt64())
+	assert.Equal(t, float64(512), dicom.MustNewElement(dicomtag.Rows, uint16(512)).MustGetFloat64())
+	assert.Equal(t, float64(-3), dicom.MustNewElement(dicomtag.ReferencePixelX0, int32(-3)).MustGetFloat64())
+	assert.Equal(t, []float64{1, 4294967295},
+		dicom.MustNewElement(dicomtag.SimpleFrameList, uint32(1), uint32(4294967295)).MustGetFloat64s())
+	_, err = dicom.MustNewElement(dicomtag.SliceThickness, "1,5").GetFloat64()
+	assert.Error(t, err)
+}
+
+func TestNumericElementFromFile(t *testing.T) {
+	ds := mustReadFile(t, "examples/CT-MONO2-16-ort.dcm", dicom.ReadOptions{})
+	elem, err := ds.FindElementByTag(dicomtag.PixelSpacing)
+	require.NoError(t, err)
+	spacing, err := elem.GetFloat64s()
+	require.NoError(t, err)
+	require.Len(t, spacing, 2)
+	assert.True(t, spacing[0] > 0)
+}
@@ -0,0 +1,339 @@
+package dicom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/msz-kp/go-dicom/dicomio"
+	"github.com/msz-kp/go-dicom/dicomlog"
+	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/msz-kp/go-dicom/dicomuid"
+)
+
+// Parser reads a DICOM file one top-level element at a time. Unlike
+// ReadDataSet, it does not accumulate the elements, so the caller can stop
+// early, skip elements it isn't interested in, or forward them to another sink
+// without holding the whole file in memory.
+//
+//	p, err := dicom.NewParser(in, dicom.ReadOptions{DropPixelData: true})
+//	if err != nil {
+//		return err
+//	}
+//	for {
+//		elem, err := p.Next()
+//		if err == io.EOF {
+//			break
+//		}
+//		if err != nil {
+//			return err
+//		}
+//		... use elem ...
+//	}
+//
+// Sequences are still read in full: Next returns a SQ element together with
+// all its items.
+type Parser struct {
+	d       *dicomio.Decoder
+	options ReadOptions
+
+	// Meta elements (Tag.Group==2) read by NewParser.
+	metaElems []*Element
+	// Number of metaElems already returned by Next.
+	nextMeta int
+
+	// Private Creator elements found so far.
+	creators dicomtag.PrivateCreators
+	// Set once a SpecificCharacterSet element is found.
+	charsetSet bool
+	// NumberOfFrames and ExtendedOffsetTable found so far, used to split
+	// encapsulated pixel data into frames.
+	numberOfFrames  int
+	extendedOffsets []uint64
+	// Set once Next returns a non-nil error.
+	err error
+	// Set if the input ends in the middle of an element.
+	truncated bool
+}
+
+// NewParser reads the DICOM file header and the meta elements (those with
+// Tag.Group==2) from "in", and prepares the parser for reading the rest of the
+// file. The file header is read eagerly since it defines the transfer syntax
+// of the rest of the file.
+//
+// If "in" has no file header, e.g., a bare dataset or an ACR-NEMA 2.0 file,
+// NewParser guesses the transfer syntax from the first element, and
+// MetaElements will contain a synthesized TransferSyntaxUID element.
+func NewParser(in io.Reader, options ReadOptions) (*Parser, error) {
+	d := dicomio.NewDecoder(in, binary.LittleEndian, dicomio.ExplicitVR)
+	metaElems := readFileHeader(d)
+	if d.Error() != nil {
+		return nil, d.Error()
+	}
+	// Change the transfer syntax for the rest of the file.
+	meta := &DataSet{Elements: metaElems}
+	endian, implicit, err := getTransferSyntax(meta)
+	if err != nil {
+		return nil, err
+	}
+	d.PushTransferSyntax(endian, implicit)
+	if uid, _ := getTransferSyntaxUID(meta); uid == dicomuid.DeflatedExplicitVRLittleEndian {
+		// The file meta group is never compressed, but everything
+		// after it is. PS3.5 A.5.
+		d.StartInflating()
+	}
+	return &Parser{
+		d:         d,
+		options:   options,
+		metaElems: metaElems,
+		creators:  dicomtag.PrivateCreators{},
+	}, nil
+}
+
+// readFileHeader reads the file header and returns the meta elements. Besides
+// DICOM Part 10 files, it accepts a bare dataset or an ACR-NEMA file, which
+// have no preamble nor "DICM" magic word. For those, the transfer syntax is
+// guessed from the first element, and a TransferSyntaxUID element is
+// synthesized. Errors are reported through d.Error().
+func readFileHeader(d *dicomio.Decoder) []*Element {
+	if head := d.Peek(132); len(head) == 132 && string(head[128:]) == "DICM" {
+		return ParseFileHeader(d)
+	}
+	var metaElems []*Element
+	d.PushTransferSyntax(binary.LittleEndian, dicomio.ExplicitVR)
+	if string(d.Peek(4)) == "DICM" {
+		// Part 10 file stripped of its preamble.
+		d.Skip(4)
+		metaElems = readMetaElements(d)
+	} else {
+		// Meta elements without the magic word. They are encoded in
+		// explicit little endian, just like in a Part 10 file.
+		for {
+			head := d.Peek(2)
+			if len(head) < 2 || binary.LittleEndian.Uint16(head) != dicomtag.MetadataGroup {
+				break
+			}
+			elem := ReadElement(d, ReadOptions{})
+			if d.Error() != nil {
+				break
+			}
+			metaElems = append(metaElems, elem)
+		}
+	}
+	d.PopTransferSyntax()
+	if d.Error() != nil {
+		return nil
+	}
+	if _, err := FindElementByTag(metaElems, dicomtag.TransferSyntaxUID); err == nil {
+		return metaElems
+	}
+	transferSyntaxUID, ok := guessTransferSyntax(d.Peek(8))
+	if !ok {
+		d.SetErrorf("Keyword 'DICM' not found in the header, and the input doesn't look like a DICOM dataset")
+		return nil
+	}
+	dicomlog.Vprintf(1, "dicom.NewParser: File header not found. Guessed transfer syntax %v", transferSyntaxUID)
+	return append(metaElems, MustNewElement(dicomtag.TransferSyntaxUID, transferSyntaxUID))
+}
+
+// VRs defined in PS3.5 6.2, plus the pseudo VR "NA" used by items.
+var knownVRs = map[string]bool{
+	"AE": true, "AS": true, "AT": true, "CS": true, "DA": true, "DS": true,
+	"DT": true, "FL": true, "FD": true, "IS": true, "LO": true, "LT": true,
+	"OB": true, "OD": true, "OF": true, "OL": true, "OV": true, "OW": true,
+	"PN": true, "SH": true, "SL": true, "SQ": true, "SS": true, "ST": true,
+	"SV": true, "TM": true, "UC": true, "UI": true, "UL": true, "UN": true,
+	"UR": true, "US": true, "UT": true, "UV": true, "NA": true,
+}
+
+// guessTransferSyntax guesses the transfer syntax of a dataset that lacks the
+// file meta group, given the first 8 bytes of the dataset. The byte order is
+// the one that yields the smaller group number, and the VR is explicit if the
+// bytes that follow the tag spell a VR. It returns false if the bytes don't
+// look like a DICOM element.
+func guessTransferSyntax(head []byte) (string, bool) {
+	if len(head) < 8 {
+		return "", false
+	}
+	var bo binary.ByteOrder = binary.LittleEndian
+	if binary.BigEndian.Uint16(head) < binary.LittleEndian.Uint16(head) {
+		bo = binary.BigEndian
+	}
+	if knownVRs[string(head[4:6])] {
+		if bo == binary.BigEndian {
+			return dicomuid.ExplicitVRBigEndian, true
+		}
+		return dicomuid.ExplicitVRLittleEndian, true
+	}
+	// There's no implicit big endian transfer syntax.
+	if bo == binary.LittleEndian {
+		tag := dicomtag.Tag{Group: bo.Uint16(head), Element: bo.Uint16(head[2:])}
+		if _, err := dicomtag.Find(tag); err == nil {
+			return dicomuid.ImplicitVRLittleEndian, true
+		}
+	}
+	return "", false
+}
+
+// MetaElements returns the meta elements (those with Tag.Group==2) found in
+// the file header.
+func (p *Parser) MetaElements() []*Element {
+	return p.metaElems
+}
+
+// BytesRead returns the number of bytes consumed from the input so far.
+func (p *Parser) BytesRead() int64 {
+	return p.d.BytesRead()
+}
+
+// Next returns the next top-level element in the file. The meta elements are
+// returned first, followed by the dataset elements in order of appearance.
+// Options.ReturnTags, DropPixelData and StopAtTag are honored in the same way
+// as in ReadDataSet.
+//
+// Next returns io.EOF when there are no more elements to read, and
+// io.ErrUnexpectedEOF if the input ends in the middle of an element. On parse
+// error, it returns the first error found by the parser, and every subsequent
+// call returns the same error.
+func (p *Parser) Next() (*Element, error) {
+	if p.nextMeta < len(p.metaElems) {
+		elem := p.metaElems[p.nextMeta]
+		p.nextMeta++
+		return elem, nil
+	}
+	for p.err == nil {
+		if p.d.EOF() {
+			p.err = p.d.Error()
+			if p.err == nil {
+				p.err = io.EOF
+			} else if p.err == io.EOF {
+				// The decoder hit the end of the input in the middle of
+				// an element.
+				p.err = io.ErrUnexpectedEOF
+				p.truncated = true
+			}
+			break
+		}
+		startLen := p.d.BytesRead()
+		elem := readElement(p.d, p.options, p.creators)
+		if p.d.BytesRead() <= startLen { // Avoid silent infinite looping.
+			panic(fmt.Sprintf("ReadElement failed to consume data: position %d: %v", startLen, p.d.Error()))
+		}
+		if elem == endOfDataElement {
+			// element is a pixel data and was dropped by options
+			p.err = io.EOF
+			break
+		}
+		if elem == nil {
+			// Parse error. It's reported by p.d.Error() on the next
+			// iteration.
+			continue
+		}
+		p.processElement(elem)
+		if p.options.ReturnTags == nil || tagInList(elem.Tag, p.options.ReturnTags) {
+			return elem, nil
+		}
+	}
+	return nil, p.err
+}
+
+// processElement updates the parser state after reading a top-level element,
+// and normalizes its string values.
+func (p *Parser) processElement(elem *Element) {
+	recordPrivateCreator(p.creators, elem)
+	p.splitFrames(elem)
+	if setSpecificCharacterSet(p.d, elem, p.options.CP1250Fix) {
+		p.charsetSet = true
+	}
+
+	// Если это строковый элемент и кодировка не была установлена,
+	// пытаемся автоматически определить кириллическую кодировку
+	if !p.charsetSet && elem.Value != nil && len(elem.Value) > 0 {
+		if strVal, ok := elem.Value[0].(string); ok && strVal != "" {
+			// Проверяем, есть ли кракозябры (неправильно декодированные символы)
+			if containsGarbage(strVal) {
+				// Пытаемся декодировать с разными кириллическими кодировками
+				decoded := detectCyrillicEncoding(strVal, p.options.DefaultCyrillicEncoding)
+				if decoded != strVal {
+					elem.Value = []interface{}{decoded}
+				}
+			}
+		}
+	}
+
+	// Обрабатываем элементы типа DS с множественными значениями
+	if elem.VR == "DS" {
+		processMultiValueDSElement(elem)
+	}
+
+	// Очистка строковых значений от непечатаемых символов, сохраняя множественные значения
+	if elem.Value != nil {
+		cleanValues := make([]interface{}, len(elem.Value))
+		for i, value := range elem.Value {
+			if strVal, ok := value.(string); ok {
+				cleanValues[i] = FilterNonPrintable(strVal)
+			} else {
+				cleanValues[i] = value
+			}
+		}
+		elem.Value = cleanValues
+	}
+}
+
+// splitFrames records the elements that describe the frames of the pixel data,
+// and when "elem" is encapsulated pixel data without a basic offset table,
+// groups its fragments into frames using them.
+func (p *Parser) splitFrames(elem *Element) {
+	switch elem.Tag {
+	case dicomtag.NumberOfFrames:
+		if n, err := elem.GetInt64(); err == nil && n > 0 {
+			p.numberOfFrames = int(n)
+		}
+	case dicomtag.ExtendedOffsetTable:
+		p.extendedOffsets = nil
+		for _, v := range elem.Value {
+			if offset, ok := v.(uint64); ok {
+				p.extendedOffsets = append(p.extendedOffsets, offset)
+			}
+		}
+	case dicomtag.PixelData:
+		if !elem.UndefinedLength || len(elem.Value) != 1 {
+			return
+		}
+		image, ok := elem.Value[0].(PixelDataInfo)
+		if !ok || len(image.Offsets) > 0 {
+			return
+		}
+		frames, err := inferFrames(image.Frames, p.extendedOffsets, p.numberOfFrames)
+		if err != nil {
+			dicomlog.Vprintf(1, "dicom.Parser: %v. Treating each fragment as a frame", err)
+			return
+		}
+		image.Frames = frames
+		elem.Value[0] = image
+	}
+}
+
+// setSpecificCharacterSet sets the []byte -> string decoder for the elements
+// that follow "elem", if it is a SpecificCharacterSet element. It's sad that
+// SpecificCharacterSet isn't part of metadata, but is part of regular attrs,
+// so we need to watch out for multiple occurrences of this type of elements.
+// The caller is responsible for scoping the change to the enclosing dataset or
+// item. It returns true if the decoder is changed.
+func setSpecificCharacterSet(d *dicomio.Decoder, elem *Element, cp1250Fix bool) bool {
+	if elem.Tag != dicomtag.SpecificCharacterSet {
+		return false
+	}
+	encodingNames, err := elem.GetCleanStrings()
+	if err != nil {
+		d.SetError(err)
+		return false
+	}
+	cs, err := dicomio.ParseSpecificCharacterSet(encodingNames, cp1250Fix)
+	if err != nil {
+		d.SetError(err)
+		return false
+	}
+	d.SetCodingSystem(cs)
+	return true
+}
@@ -1,7 +1,11 @@
 package dicom_test
 
 import (
+	"bytes"
 	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
 	"reflect"
 	"testing"
 
@@ -95,3 +99,124 @@ func TestNewElement(t *testing.T) {
 	elem, err = dicom.NewElement(dicomtag.TriggerSamplePosition, "foo")
 	require.Error(t, err)
 }
+
+func TestParserNext(t *testing.T) {
+	ds := mustReadFile(t, "examples/IM-0001-0001.dcm", dicom.ReadOptions{})
+	in, err := os.Open("examples/IM-0001-0001.dcm")
+	require.NoError(t, err)
+	defer in.Close()
+	p, err := dicom.NewParser(in, dicom.ReadOptions{})
+	require.NoError(t, err)
+	require.Len(t, p.MetaElements(), 8)
+	var elems []*dicom.Element
+	for {
+		elem, err := p.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		elems = append(elems, elem)
+	}
+	require.Equal(t, len(ds.Elements), len(elems))
+	for i := range elems {
+		assert.Equal(t, ds.Elements[i].String(), elems[i].String())
+	}
+	// Further calls keep returning EOF.
+	_, err = p.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestParserStopEarly(t *testing.T) {
+	in, err := os.Open("examples/IM-0001-0001.dcm")
+	require.NoError(t, err)
+	defer in.Close()
+	p, err := dicom.NewParser(in, dicom.ReadOptions{DropPixelData: true})
+	require.NoError(t, err)
+	for {
+		elem, err := p.Next()
+		require.NoError(t, err)
+		if elem.Tag == dicomtag.PatientName {
+			assert.Equal(t, "TOUTATIX", elem.MustGetString())
+			break
+		}
+	}
+	assert.True(t, p.BytesRead() < 100000)
+}
+
+func TestParserTruncated(t *testing.T) {
+	data, err := ioutil.ReadFile("examples/IM-0001-0001.dcm")
+	require.NoError(t, err)
+	data = data[:len(data)-10]
+	p, err := dicom.NewParser(bytes.NewReader(data), dicom.ReadOptions{})
+	require.NoError(t, err)
+	for err == nil {
+		_, err = p.Next()
+	}
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// ReadDataSet keeps returning io.EOF.
+	ds, err := dicom.ReadDataSetInBytes(data, dicom.ReadOptions{})
+	assert.Equal(t, io.EOF, err)
+	assert.True(t, ds.Has(dicomtag.PatientName))
+}
+
+func TestWriteUndefinedLengthUN(t *testing.T) {
+	// A private sequence whose VR is unknown. Its items are encoded in implicit
+	// little endian even though the file is explicit. PS3.5 6.2.2.
+	privateSeq := &dicom.Element{
+		Tag:             dicomtag.Tag{Group: 0x0029, Element: 0x1010},
+		VR:              "UN",
+		UndefinedLength: true,
+		Value: []interface{}{
+			&dicom.Element{
+				Tag:             dicomtag.Item,
+				VR:              "NA",
+				UndefinedLength: true,
+				Value:           []interface{}{dicom.MustNewElement(dicomtag.PatientID, "1234")},
+			},
+		},
+	}
+	e := dicomio.NewBytesEncoder(binary.BigEndian, dicomio.ExplicitVR)
+	dicom.WriteElement(e, privateSeq, &dicom.WriteOptSet{})
+	dicom.WriteElement(e, dicom.MustNewElement(dicomtag.PatientName, "Bob"), &dicom.WriteOptSet{})
+	d := dicomio.NewBytesDecoder(e.Bytes(), binary.BigEndian, dicomio.ExplicitVR)
+	elem := dicom.ReadElement(d, dicom.ReadOptions{})
+	require.NoError(t, d.Error())
+	assert.Equal(t, privateSeq.String(), elem.String())
+	elem = dicom.ReadElement(d, dicom.ReadOptions{})
+	require.NoError(t, d.Error())
+	assert.Equal(t, "Bob", elem.MustGetString())
+	require.NoError(t, d.Finish())
+}
+
+func TestReadPrivateElementImplicit(t *testing.T) {
+	creator := &dicom.Element{Tag: dicomtag.Tag{Group: 0x0029, Element: 0x0010}, VR: "LO", Value: []interface{}{"SIEMENS CSA HEADER"}}
+	csa := &dicom.Element{Tag: dicomtag.Tag{Group: 0x0029, Element: 0x1010}, VR: "OB", Value: []interface{}{[]byte("SV10\x04\x03\x02\x01")}}
+	ds := dicom.DataSet{Elements: []*dicom.Element{
+		dicom.MustNewElement(dicomtag.TransferSyntaxUID, dicomuid.ImplicitVRLittleEndian),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPClassUID, "1.2.840.10008.5.1.4.1.1.4"),
+		dicom.MustNewElement(dicomtag.MediaStorageSOPInstanceUID, "1.2.3.4.5.6.7"),
+		creator,
+		csa,
+		dicom.MustNewElement(dicomtag.ReferencedSeriesSequence,
+			dicom.MustNewElement(dicomtag.Item, creator, csa)),
+	}}
+	var buf bytes.Buffer
+	require.NoError(t, dicom.WriteDataSet(&buf, &ds))
+	ds2, err := dicom.ReadDataSet(&buf, dicom.ReadOptions{})
+	require.NoError(t, err)
+
+	elem, err := ds2.FindElementByTag(csa.Tag)
+	require.NoError(t, err)
+	assert.Equal(t, "OB", elem.VR)
+	assert.Equal(t, "SIEMENS CSA HEADER", elem.PrivateCreator)
+	assert.Equal(t, []byte("SV10\x04\x03\x02\x01"), elem.Value[0])
+	assert.Contains(t, elem.String(), "(0029,1010)[SIEMENS CSA HEADER:CSAImageHeaderInfo] OB")
+
+	elem, err = ds2.FindElementByTag(dicomtag.ReferencedSeriesSequence)
+	require.NoError(t, err)
+	elem, err = dicom.FindElementByTag(elem.Value[0].(*dicom.Element).GetElements(), csa.Tag)
+	require.NoError(t, err)
+	assert.Equal(t, "OB", elem.VR)
+	assert.Equal(t, "SIEMENS CSA HEADER", elem.PrivateCreator)
+}
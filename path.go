@@ -0,0 +1,271 @@
+package dicom
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/msz-kp/go-dicom/dicomtag"
+)
+
+// Path is a parsed attribute path that locates elements nested in sequences,
+// e.g.,
+//
+//	ReferencedSeriesSequence[0].ReferencedInstanceSequence[*].ReferencedSOPInstanceUID
+//	(0008,1115)[0].(0020,000E)
+//
+// A path is a list of steps separated by '.'. Each step names an element,
+// either by its name in the dictionary or by its "(gggg,eeee)" hex form. All
+// steps but the last must name a sequence, and must be followed by an item
+// index: "[n]" selects the n'th item (0-based), and "[*]" selects every item.
+// The last step may also have an index, in which case the path locates the
+// items themselves.
+type Path []PathStep
+
+// PathStep is one step of a Path.
+type PathStep struct {
+	Tag dicomtag.Tag
+	// Index of the item in the sequence named by Tag, or one of NoItem and
+	// AnyItem.
+	Index int
+}
+
+const (
+	// NoItem is the PathStep.Index of a step that names the element itself.
+	NoItem = -1
+	// AnyItem is the PathStep.Index of a step that selects every item, i.e.,
+	// "[*]".
+	AnyItem = -2
+)
+
+// ParsePath parses a path expression. See Path for the syntax.
+func ParsePath(s string) (Path, error) {
+	var p Path
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("dicom.ParsePath: empty path")
+	}
+	parts := strings.Split(s, ".")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		step := PathStep{Index: NoItem}
+		if j := strings.IndexByte(part, '['); j >= 0 {
+			if !strings.HasSuffix(part, "]") {
+				return nil, fmt.Errorf("dicom.ParsePath: '%s': missing ']' in '%s'", s, part)
+			}
+			index := part[j+1 : len(part)-1]
+			if index == "*" {
+				step.Index = AnyItem
+			} else {
+				n, err := strconv.Atoi(index)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("dicom.ParsePath: '%s': invalid item index '%s'", s, index)
+				}
+				step.Index = n
+			}
+			part = part[:j]
+		} else if i < len(parts)-1 {
+			return nil, fmt.Errorf("dicom.ParsePath: '%s': item index missing after '%s'", s, part)
+		}
+		tag, err := dicomtag.ParseTag(part)
+		if err != nil {
+			return nil, fmt.Errorf("dicom.ParsePath: '%s': %v", s, err)
+		}
+		step.Tag = tag
+		p = append(p, step)
+	}
+	return p, nil
+}
+
+// MustParsePath is similar to ParsePath, but panics on error.
+func MustParsePath(s string) Path {
+	p, err := ParsePath(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String returns the path in canonical form, with each step in the
+// "(gggg,eeee)" form.
+func (p Path) String() string {
+	parts := make([]string, len(p))
+	for i, step := range p {
+		parts[i] = step.String()
+	}
+	return strings.Join(parts, ".")
+}
+
+// String returns the step in the "(gggg,eeee)[n]" form.
+func (step PathStep) String() string {
+	s := fmt.Sprintf("(%04X,%04X)", step.Tag.Group, step.Tag.Element)
+	switch {
+	case step.Index == AnyItem:
+		s += "[*]"
+	case step.Index >= 0:
+		s += fmt.Sprintf("[%d]", step.Index)
+	}
+	return s
+}
+
+// selectItems returns the items of the sequence "elem" selected by the step.
+func (step PathStep) selectItems(elem *Element) ([]*Element, error) {
+	if !elem.isSequence() {
+		return nil, fmt.Errorf("%v: not a sequence", dicomtag.DebugString(elem.Tag))
+	}
+	items := elem.GetElements()
+	if step.Index == AnyItem {
+		return items, nil
+	}
+	if step.Index >= 0 && step.Index < len(items) {
+		return items[step.Index : step.Index+1], nil
+	}
+	return nil, nil
+}
+
+// Find returns the elements located by the path in "ds". If the path contains
+// "[*]", it may return multiple elements, in order of appearance. An item index
+// out of range, or a missing element, yields no match instead of an error.
+func (p Path) Find(ds *DataSet) ([]*Element, error) {
+	var matches []*Element
+	// The datasets or items to apply the current step to.
+	current := []*DataSet{ds}
+	for i, step := range p {
+		var next []*DataSet
+		for _, d := range current {
+			elem, err := d.FindElementByTag(step.Tag)
+			if err != nil {
+				continue
+			}
+			if step.Index == NoItem {
+				matches = append(matches, elem)
+				continue
+			}
+			items, err := step.selectItems(elem)
+			if err != nil {
+				return nil, err
+			}
+			if i == len(p)-1 {
+				matches = append(matches, items...)
+				continue
+			}
+			for _, item := range items {
+				next = append(next, &DataSet{Elements: item.GetElements()})
+			}
+		}
+		current = next
+	}
+	return matches, nil
+}
+
+// Set creates an element with the given values, as NewElement does, at every
+// place located by the path in "ds". If an element of the same tag exists
+// there, it is replaced. Missing sequences are created, and an index equal to
+// the number of items in the sequence appends a new item, so a path can be
+// used to build a nested structure from scratch. The last step must not have
+// an item index, and "[*]" must match at least one item. On error, "ds" is
+// left unchanged.
+func (p Path) Set(ds *DataSet, values ...interface{}) error {
+	if len(p) == 0 || p[len(p)-1].Index != NoItem {
+		return fmt.Errorf("dicom.Path.Set: %v: the last step must not have an item index", p)
+	}
+	// Check the whole path first, so that a failure doesn't leave the
+	// dataset half modified.
+	if err := p.set(ds, p, values, false); err != nil {
+		return err
+	}
+	return p.set(ds, p, values, true)
+}
+
+// set applies the remaining steps to "ds". Unless "apply" is set, it only
+// checks that they can be applied, and doesn't modify "ds".
+func (p Path) set(ds *DataSet, steps []PathStep, values []interface{}, apply bool) error {
+	step := steps[0]
+	if len(steps) == 1 {
+		elem, err := NewElement(step.Tag, values...)
+		if err != nil {
+			return err
+		}
+		if apply {
+			ds.Set(elem)
+		}
+		return nil
+	}
+	if step.Index == NoItem {
+		return fmt.Errorf("dicom.Path.Set: %v: item index missing after %v", p, dicomtag.DebugString(step.Tag))
+	}
+	seq, err := ds.FindElementByTag(step.Tag)
+	if err != nil {
+		if seq, err = NewElement(step.Tag); err != nil {
+			return err
+		}
+		if apply {
+			ds.Set(seq)
+		}
+	}
+	if !seq.isSequence() {
+		return fmt.Errorf("dicom.Path.Set: %v: %v is not a sequence", p, dicomtag.DebugString(step.Tag))
+	}
+	items := seq.GetElements()
+	switch n := len(items); {
+	case step.Index == AnyItem && n == 0:
+		return fmt.Errorf("dicom.Path.Set: %v: %v has no items", p, dicomtag.DebugString(step.Tag))
+	case step.Index > n:
+		return fmt.Errorf("dicom.Path.Set: %v: item index %d out of range; %v has %d items",
+			p, step.Index, dicomtag.DebugString(step.Tag), n)
+	case step.Index == n:
+		// When only checking, the new item isn't added to the
+		// sequence.
+		item := MustNewElement(dicomtag.Item)
+		if apply {
+			seq.Value = append(seq.Value, item)
+		}
+		items = []*Element{item}
+	default:
+		if items, err = step.selectItems(seq); err != nil {
+			return err
+		}
+	}
+	for _, item := range items {
+		itemDS, err := item.ItemDataSet()
+		if err != nil {
+			return err
+		}
+		if err := p.set(itemDS, steps[1:], values, apply); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindElementsByPath parses "path" and returns the elements it locates. See
+// Path.Find.
+func (f *DataSet) FindElementsByPath(path string) ([]*Element, error) {
+	p, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.Find(f)
+}
+
+// FindElementByPath is similar to FindElementsByPath, but it returns an error
+// unless the path locates exactly one element.
+func (f *DataSet) FindElementByPath(path string) (*Element, error) {
+	elems, err := f.FindElementsByPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(elems) != 1 {
+		return nil, fmt.Errorf("Found %d element(s) at path '%s' (expect 1)", len(elems), path)
+	}
+	return elems[0], nil
+}
+
+// SetElementByPath parses "path" and sets the element it locates to the given
+// values. See Path.Set.
+func (f *DataSet) SetElementByPath(path string, values ...interface{}) error {
+	p, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+	return p.Set(f, values...)
+}
@@ -0,0 +1,127 @@
+package dicom_test
+
+import (
+	"testing"
+
+	"github.com/msz-kp/go-dicom"
+	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newReferencedSeries(seriesUID string, instanceUIDs ...string) *dicom.Element {
+	var instances []interface{}
+	for _, uid := range instanceUIDs {
+		instances = append(instances, dicom.MustNewElement(dicomtag.Item,
+			dicom.MustNewElement(dicomtag.ReferencedSOPInstanceUID, uid)))
+	}
+	return dicom.MustNewElement(dicomtag.Item,
+		dicom.MustNewElement(dicomtag.ReferencedInstanceSequence, instances...),
+		dicom.MustNewElement(dicomtag.SeriesInstanceUID, seriesUID))
+}
+
+func TestParsePath(t *testing.T) {
+	p, err := dicom.ParsePath("ReferencedSeriesSequence[0].ReferencedInstanceSequence[*].ReferencedSOPInstanceUID")
+	require.NoError(t, err)
+	assert.Equal(t, "(0008,1115)[0].(0008,114A)[*].(0008,1155)", p.String())
+	p, err = dicom.ParsePath("(0008,1115)[12].(0020,000E)")
+	require.NoError(t, err)
+	assert.Equal(t, "(0008,1115)[12].(0020,000E)", p.String())
+
+	for _, s := range []string{
+		"",
+		"ReferencedSeriesSequence.SeriesInstanceUID",
+		"ReferencedSeriesSequence[0.SeriesInstanceUID",
+		"ReferencedSeriesSequence[-1].SeriesInstanceUID",
+		"ReferencedSeriesSequence[x].SeriesInstanceUID",
+		"NoSuchElement",
+		"ReferencedSeriesSequence[0].",
+	} {
+		_, err := dicom.ParsePath(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestFindElementsByPath(t *testing.T) {
+	ds := &dicom.DataSet{Elements: []*dicom.Element{
+		dicom.MustNewElement(dicomtag.ReferencedSeriesSequence,
+			newReferencedSeries("1.2.1", "1.2.1.1", "1.2.1.2"),
+			newReferencedSeries("1.2.2", "1.2.2.1")),
+		dicom.MustNewElement(dicomtag.PatientName, "Doe^John"),
+	}}
+	getStrings := func(path string) []string {
+		elems, err := ds.FindElementsByPath(path)
+		require.NoError(t, err, path)
+		var values []string
+		for _, elem := range elems {
+			values = append(values, elem.MustGetString())
+		}
+		return values
+	}
+	assert.Equal(t, []string{"Doe^John"}, getStrings("PatientName"))
+	assert.Equal(t, []string{"1.2.2"}, getStrings("(0008,1115)[1].(0020,000E)"))
+	assert.Equal(t, []string{"1.2.1.1", "1.2.1.2"},
+		getStrings("ReferencedSeriesSequence[0].ReferencedInstanceSequence[*].ReferencedSOPInstanceUID"))
+	assert.Equal(t, []string{"1.2.1.1", "1.2.1.2", "1.2.2.1"},
+		getStrings("ReferencedSeriesSequence[*].ReferencedInstanceSequence[*].ReferencedSOPInstanceUID"))
+	assert.Empty(t, getStrings("ReferencedSeriesSequence[2].SeriesInstanceUID"))
+	assert.Empty(t, getStrings("PatientID"))
+
+	items, err := ds.FindElementsByPath("ReferencedSeriesSequence[*]")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, dicomtag.Item, items[0].Tag)
+
+	elem, err := ds.FindElementByPath("ReferencedSeriesSequence[1].SeriesInstanceUID")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.2", elem.MustGetString())
+	_, err = ds.FindElementByPath("ReferencedSeriesSequence[*].SeriesInstanceUID")
+	assert.Error(t, err)
+	_, err = ds.FindElementsByPath("PatientName[0].PatientID")
+	assert.Error(t, err)
+}
+
+func TestSetElementByPath(t *testing.T) {
+	ds := &dicom.DataSet{Elements: []*dicom.Element{
+		dicom.MustNewElement(dicomtag.ReferencedSeriesSequence,
+			newReferencedSeries("1.2.1", "1.2.1.1", "1.2.1.2")),
+	}}
+	require.NoError(t, ds.SetElementByPath(
+		"ReferencedSeriesSequence[0].ReferencedInstanceSequence[*].ReferencedSOPInstanceUID", "9.9"))
+	elems, err := ds.FindElementsByPath("ReferencedSeriesSequence[0].ReferencedInstanceSequence[*].ReferencedSOPInstanceUID")
+	require.NoError(t, err)
+	require.Len(t, elems, 2)
+	for _, elem := range elems {
+		assert.Equal(t, "9.9", elem.MustGetString())
+	}
+
+	// Build a new sequence from scratch. The elements stay sorted.
+	require.NoError(t, ds.SetElementByPath("ReferencedImageSequence[0].ReferencedSOPInstanceUID", "1.2.3"))
+	require.NoError(t, ds.SetElementByPath("ReferencedImageSequence[0].ReferencedSOPClassUID", "1.2.840.10008.5.1.4.1.1.2"))
+	require.NoError(t, ds.SetElementByPath("ReferencedImageSequence[1].ReferencedSOPInstanceUID", "1.2.4"))
+	require.NoError(t, ds.SetElementByPath("PatientName", "Doe^John"))
+	assert.Equal(t, []dicomtag.Tag{dicomtag.ReferencedSeriesSequence, dicomtag.ReferencedImageSequence, dicomtag.PatientName},
+		dataSetTags(ds.Elements))
+	item, err := ds.FindElementByPath("ReferencedImageSequence[0]")
+	require.NoError(t, err)
+	assert.Equal(t, []dicomtag.Tag{dicomtag.ReferencedSOPClassUID, dicomtag.ReferencedSOPInstanceUID},
+		dataSetTags(item.GetElements()))
+	elem, err := ds.FindElementByPath("ReferencedImageSequence[1].ReferencedSOPInstanceUID")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.4", elem.MustGetString())
+
+	assert.Error(t, ds.SetElementByPath("ReferencedImageSequence[3].ReferencedSOPInstanceUID", "1.2.5"))
+	assert.Error(t, ds.SetElementByPath("PatientName[0].PatientID", "1"))
+	assert.Error(t, ds.SetElementByPath("ReferencedImageSequence[0]", "1"))
+	assert.Error(t, ds.SetElementByPath("PatientName", uint16(1)))
+	assert.Error(t, ds.SetElementByPath("ReferencedImageSequence[*].ReferencedSOPClassUID", uint16(1)))
+
+	// A failed Set leaves the dataset unchanged.
+	before := ds.Clone()
+	assert.Error(t, ds.SetElementByPath("ReferencedStudySequence[1].ReferencedSOPInstanceUID", "1.2.5"))
+	assert.Error(t, ds.SetElementByPath("ReferencedStudySequence[0].ReferencedSeriesSequence[*].SeriesInstanceUID", "1.2.5"))
+	assert.Error(t, ds.SetElementByPath("ReferencedStudySequence[*].ReferencedSOPInstanceUID", "1.2.5"))
+	assert.Error(t, ds.SetElementByPath("ReferencedImageSequence[2].PatientName[0].PatientID", "1"))
+	assert.True(t, before.Equal(ds))
+	assert.False(t, ds.Has(dicomtag.ReferencedStudySequence))
+}
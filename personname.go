@@ -0,0 +1,130 @@
+package dicom
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/msz-kp/go-dicom/dicomtag"
+)
+
+// PersonNameGroup is one component group of a person name. P3.5 6.2.1.
+type PersonNameGroup struct {
+	FamilyName string
+	GivenName  string
+	MiddleName string
+	NamePrefix string
+	NameSuffix string
+}
+
+// PersonName is a parsed PN value. Each group holds the same name in a
+// different representation, e.g., in romaji, kanji and hiragana for a Japanese
+// name. Groups that are absent in the value are empty.
+type PersonName struct {
+	Alphabetic  PersonNameGroup
+	Ideographic PersonNameGroup
+	Phonetic    PersonNameGroup
+}
+
+// ParsePersonName parses a PN value of form
+// "Family^Given^Middle^Prefix^Suffix=Ideographic=Phonetic", where the
+// trailing components and groups may be omitted. Leading and trailing spaces
+// of each component are dropped. It returns an error if the value has more
+// than three groups, or a group has more than five components.
+func ParsePersonName(s string) (PersonName, error) {
+	var p PersonName
+	groups := strings.Split(s, "=")
+	if len(groups) > 3 {
+		return p, fmt.Errorf("dicom.ParsePersonName: '%s' has %d component groups, expect at most 3", s, len(groups))
+	}
+	for i, g := range groups {
+		components := strings.Split(g, "^")
+		if len(components) > 5 {
+			return p, fmt.Errorf("dicom.ParsePersonName: '%s' has %d components in a group, expect at most 5", s, len(components))
+		}
+		dest := []*PersonNameGroup{&p.Alphabetic, &p.Ideographic, &p.Phonetic}[i]
+		fields := []*string{&dest.FamilyName, &dest.GivenName, &dest.MiddleName, &dest.NamePrefix, &dest.NameSuffix}
+		for j, c := range components {
+			*fields[j] = strings.TrimSpace(c)
+		}
+	}
+	return p, nil
+}
+
+// String formats the group as "Family^Given^Middle^Prefix^Suffix", with the
+// trailing empty components dropped.
+func (g PersonNameGroup) String() string {
+	return strings.TrimRight(strings.Join([]string{g.FamilyName, g.GivenName, g.MiddleName, g.NamePrefix, g.NameSuffix}, "^"), "^")
+}
+
+// IsEmpty returns true if all the components of the group are empty.
+func (g PersonNameGroup) IsEmpty() bool {
+	return g == PersonNameGroup{}
+}
+
+// String formats the name as a PN value, with the trailing empty components and
+// groups dropped. It's the inverse of ParsePersonName.
+func (p PersonName) String() string {
+	return strings.TrimRight(strings.Join([]string{p.Alphabetic.String(), p.Ideographic.String(), p.Phonetic.String()}, "="), "=")
+}
+
+// NewPersonNameElement creates a new element of VR PN, such as PatientName, with
+// the given values formatted by PersonName.String.
+func NewPersonNameElement(tag dicomtag.Tag, values ...PersonName) (*Element, error) {
+	if err := checkDictionaryVR(tag, "PN"); err != nil {
+		return nil, err
+	}
+	strs := make([]interface{}, len(values))
+	for i, v := range values {
+		strs[i] = v.String()
+	}
+	return NewElement(tag, strs...)
+}
+
+// GetPersonNames parses the string values stored in the element by
+// ParsePersonName. It returns an error if the element doesn't store strings,
+// or a value is not a valid PN.
+func (e *Element) GetPersonNames() ([]PersonName, error) {
+	strs, err := e.GetStrings()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]PersonName, len(strs))
+	for i, s := range strs {
+		if names[i], err = ParsePersonName(s); err != nil {
+			return nil, fmt.Errorf("%v: %v", dicomtag.DebugString(e.Tag), err)
+		}
+	}
+	return names, nil
+}
+
+// MustGetPersonNames is similar to GetPersonNames, but crashes the process on
+// error.
+func (e *Element) MustGetPersonNames() []PersonName {
+	names, err := e.GetPersonNames()
+	if err != nil {
+		panic(err)
+	}
+	return names
+}
+
+// GetPersonName is similar to GetPersonNames, but it returns an error if the
+// element contains zero or >1 values.
+func (e *Element) GetPersonName() (PersonName, error) {
+	if len(e.Value) != 1 {
+		return PersonName{}, fmt.Errorf("Found %d value(s) in getpersonname (expect 1): %v", len(e.Value), e)
+	}
+	names, err := e.GetPersonNames()
+	if err != nil {
+		return PersonName{}, err
+	}
+	return names[0], nil
+}
+
+// MustGetPersonName is similar to GetPersonName, but panics on error.
+func (e *Element) MustGetPersonName() PersonName {
+	name, err := e.GetPersonName()
+	if err != nil {
+		panic(err)
+	}
+	return name
+}
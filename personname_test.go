@@ -0,0 +1,84 @@
+package dicom_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/msz-kp/go-dicom"
+	"github.com/msz-kp/go-dicom/dicomio"
+	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePersonName(t *testing.T) {
+	goodNames := []struct {
+		str  string
+		name dicom.PersonName
+	}{
+		{"", dicom.PersonName{}},
+		{"Doe", dicom.PersonName{Alphabetic: dicom.PersonNameGroup{FamilyName: "Doe"}}},
+		{"Adams^John Robert Quincy^^Rev.^B.A. M.Div.", dicom.PersonName{
+			Alphabetic: dicom.PersonNameGroup{FamilyName: "Adams", GivenName: "John Robert Quincy", NamePrefix: "Rev.", NameSuffix: "B.A. M.Div."}}},
+		{"Morrison-Jones^Susan^^^Ph.D., Chief Executive Officer", dicom.PersonName{
+			Alphabetic: dicom.PersonNameGroup{FamilyName: "Morrison-Jones", GivenName: "Susan", NameSuffix: "Ph.D., Chief Executive Officer"}}},
+		{"Yamada^Tarou=山田^太郎=やまだ^たろう", dicom.PersonName{
+			Alphabetic:  dicom.PersonNameGroup{FamilyName: "Yamada", GivenName: "Tarou"},
+			Ideographic: dicom.PersonNameGroup{FamilyName: "山田", GivenName: "太郎"},
+			Phonetic:    dicom.PersonNameGroup{FamilyName: "やまだ", GivenName: "たろう"}}},
+		{"=山田^太郎", dicom.PersonName{
+			Ideographic: dicom.PersonNameGroup{FamilyName: "山田", GivenName: "太郎"}}},
+	}
+	for _, n := range goodNames {
+		name, err := dicom.ParsePersonName(n.str)
+		require.NoError(t, err, n.str)
+		assert.Equal(t, n.name, name)
+		assert.Equal(t, n.str, name.String())
+	}
+
+	// Trailing delimiters and padding are dropped.
+	name, err := dicom.ParsePersonName("Doe^John^^^== ")
+	require.NoError(t, err)
+	assert.Equal(t, "Doe^John", name.String())
+	assert.True(t, name.Ideographic.IsEmpty())
+
+	for _, s := range []string{"A=B=C=D", "A^B^C^D^E^F"} {
+		_, err := dicom.ParsePersonName(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestPersonNameElement(t *testing.T) {
+	names := []dicom.PersonName{
+		{Alphabetic: dicom.PersonNameGroup{FamilyName: "Doe", GivenName: "John", NamePrefix: "Dr."}},
+		{Alphabetic: dicom.PersonNameGroup{FamilyName: "Smith"}},
+	}
+	elem, err := dicom.NewPersonNameElement(dicomtag.ReferringPhysicianName, names...)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Doe^John^^Dr.", "Smith"}, elem.MustGetStrings())
+
+	e := dicomio.NewBytesEncoder(binary.LittleEndian, dicomio.ExplicitVR)
+	dicom.WriteElement(e, elem, &dicom.WriteOptSet{})
+	require.NoError(t, e.Error())
+	d := dicomio.NewDecoder(bytes.NewReader(e.Bytes()), binary.LittleEndian, dicomio.ExplicitVR)
+	elem = dicom.ReadElement(d, dicom.ReadOptions{})
+	require.NoError(t, d.Error())
+	assert.Equal(t, names, elem.MustGetPersonNames())
+	_, err = elem.GetPersonName()
+	assert.Error(t, err)
+
+	_, err = dicom.NewPersonNameElement(dicomtag.PatientID, names[0])
+	assert.Error(t, err)
+	_, err = dicom.MustNewElement(dicomtag.Rows, uint16(1)).GetPersonNames()
+	assert.Error(t, err)
+}
+
+func TestPersonNameFromFile(t *testing.T) {
+	ds := mustReadFile(t, "examples/IM-0001-0001.dcm", dicom.ReadOptions{})
+	elem, err := ds.FindElementByTag(dicomtag.PatientName)
+	require.NoError(t, err)
+	name, err := elem.GetPersonName()
+	require.NoError(t, err)
+	assert.Equal(t, elem.MustGetString(), name.String())
+}
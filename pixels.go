@@ -0,0 +1,374 @@
+package dicom
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/msz-kp/go-dicom/dicomuid"
+)
+
+// PixelInfo describes the layout of the pixel data of an image, as found in
+// the Image Pixel module. P3.3 C.7.6.3.
+type PixelInfo struct {
+	Rows, Columns       int
+	BitsAllocated       int
+	BitsStored          int
+	HighBit             int
+	PixelRepresentation int // 0 for unsigned, 1 for two's complement.
+	SamplesPerPixel     int
+	PlanarConfiguration int
+	NumberOfFrames      int
+	// PhotometricInterpretation, e.g., "MONOCHROME2" or "RGB". Empty if the
+	// dataset doesn't have one.
+	PhotometricInterpretation string
+}
+
+// Pixels is the pixel data decoded by DecodePixels.
+type Pixels struct {
+	PixelInfo
+	// Frames[i] is the i'th frame. Its type depends on the pixel data:
+	//
+	//   BitsAllocated=1:          []uint8, one 0 or 1 per sample
+	//   BitsAllocated=8,  unsigned:   []uint8
+	//   BitsAllocated=8,  signed:     []int16
+	//   BitsAllocated=16, unsigned:   []uint16
+	//   BitsAllocated=16, signed:     []int16
+	//   BitsAllocated=32, unsigned:   []uint32
+	//   BitsAllocated=32, signed:     []int32
+	//   FloatPixelData:           []float32
+	//   DoubleFloatPixelData:     []float64
+	//
+	// Each frame has Rows*Columns*SamplesPerPixel values, in row-major
+	// order. Samples of a pixel are always adjacent, i.e., as if
+	// PlanarConfiguration were 0, regardless of how they were stored. For
+	// YBR_FULL_422 and YBR_PARTIAL_422, the chroma samples are duplicated, so
+	// each pixel has its own Y, Cb and Cr. Use ConvertToRGB to convert color
+	// images to RGB. Integer values are masked to BitsStored, and sign-extended if
+	// PixelRepresentation==1.
+	Frames []interface{}
+}
+
+// PixelsPerFrame returns Rows*Columns.
+func (info PixelInfo) PixelsPerFrame() int {
+	return info.Rows * info.Columns
+}
+
+// NewPixelInfo reads the Image Pixel module attributes from the dataset.
+// Rows and Columns are required. The others default to the values of a single
+// frame, single sample, unsigned image with all the allocated bits stored.
+func NewPixelInfo(ds *DataSet) (PixelInfo, error) {
+	var info PixelInfo
+	attrs := []struct {
+		tag      dicomtag.Tag
+		value    *int
+		required bool
+	}{
+		{dicomtag.Rows, &info.Rows, true},
+		{dicomtag.Columns, &info.Columns, true},
+		{dicomtag.BitsAllocated, &info.BitsAllocated, false},
+		{dicomtag.BitsStored, &info.BitsStored, false},
+		{dicomtag.HighBit, &info.HighBit, false},
+		{dicomtag.PixelRepresentation, &info.PixelRepresentation, false},
+		{dicomtag.SamplesPerPixel, &info.SamplesPerPixel, false},
+		{dicomtag.PlanarConfiguration, &info.PlanarConfiguration, false},
+		{dicomtag.NumberOfFrames, &info.NumberOfFrames, false},
+	}
+	for _, attr := range attrs {
+		*attr.value = -1
+		elem, err := ds.FindElementByTag(attr.tag)
+		if err != nil {
+			if attr.required {
+				return info, err
+			}
+			continue
+		}
+		v, err := elem.GetInt64()
+		if err != nil {
+			return info, err
+		}
+		if v < 0 || v > 1<<31-1 {
+			return info, fmt.Errorf("%v: invalid value %d", dicomtag.DebugString(attr.tag), v)
+		}
+		*attr.value = int(v)
+	}
+	if elem, err := ds.FindElementByTag(dicomtag.PhotometricInterpretation); err == nil {
+		if s, err := elem.GetString(); err == nil {
+			info.PhotometricInterpretation = s
+		}
+	}
+	if info.BitsAllocated < 0 {
+		info.BitsAllocated = 8
+		if _, err := ds.FindElementByTag(dicomtag.FloatPixelData); err == nil {
+			info.BitsAllocated = 32
+		} else if _, err := ds.FindElementByTag(dicomtag.DoubleFloatPixelData); err == nil {
+			info.BitsAllocated = 64
+		}
+	}
+	if info.BitsStored < 0 {
+		info.BitsStored = info.BitsAllocated
+	}
+	if info.HighBit < 0 {
+		info.HighBit = info.BitsStored - 1
+	}
+	if info.PixelRepresentation < 0 {
+		info.PixelRepresentation = 0
+	}
+	if info.SamplesPerPixel < 0 {
+		info.SamplesPerPixel = 1
+	}
+	if info.PlanarConfiguration < 0 {
+		info.PlanarConfiguration = 0
+	}
+	if info.NumberOfFrames <= 0 {
+		info.NumberOfFrames = 1
+	}
+	return info, nil
+}
+
+func (info PixelInfo) validate() error {
+	switch {
+	case info.Rows == 0 || info.Columns == 0 || info.SamplesPerPixel == 0:
+		return fmt.Errorf("dicom.DecodePixels: empty image: %+v", info)
+	case info.BitsStored == 0 || info.BitsStored > info.BitsAllocated:
+		return fmt.Errorf("dicom.DecodePixels: invalid BitsStored %d for BitsAllocated %d", info.BitsStored, info.BitsAllocated)
+	case info.HighBit >= info.BitsAllocated || info.HighBit+1 < info.BitsStored:
+		return fmt.Errorf("dicom.DecodePixels: invalid HighBit %d for BitsStored %d", info.HighBit, info.BitsStored)
+	case info.PixelRepresentation > 1:
+		return fmt.Errorf("dicom.DecodePixels: invalid PixelRepresentation %d", info.PixelRepresentation)
+	case info.PlanarConfiguration > 1:
+		return fmt.Errorf("dicom.DecodePixels: invalid PlanarConfiguration %d", info.PlanarConfiguration)
+	case info.isYBR422() && info.SamplesPerPixel == 3 && info.Columns%2 != 0:
+		return fmt.Errorf("dicom.DecodePixels: %s requires an even number of columns, but found %d",
+			info.PhotometricInterpretation, info.Columns)
+	}
+	return nil
+}
+
+// DecodePixels decodes the native, i.e., uncompressed, pixel data of the
+// dataset into typed frames. It reads the PixelData element, or the
+// FloatPixelData or DoubleFloatPixelData element if the former is missing, and
+// the Image Pixel module attributes; see NewPixelInfo.
+//
+// Encapsulated pixel data is supported only in the RLE Lossless transfer
+// syntax.
+func DecodePixels(ds *DataSet) (*Pixels, error) {
+	info, err := NewPixelInfo(ds)
+	if err != nil {
+		return nil, fmt.Errorf("dicom.DecodePixels: %v", err)
+	}
+	if err := info.validate(); err != nil {
+		return nil, err
+	}
+	pixels := &Pixels{PixelInfo: info}
+	samplesPerFrame := info.PixelsPerFrame() * info.SamplesPerPixel
+	if elem, err := ds.FindElementByTag(dicomtag.PixelData); err == nil {
+		if elem.UndefinedLength {
+			if uid, _ := getTransferSyntaxUID(ds); uid != dicomuid.RLELossless {
+				return nil, fmt.Errorf("dicom.DecodePixels: encapsulated pixel data in transfer syntax '%s' is not supported", uid)
+			}
+			frames, rleInfo, err := decodeRLEPixelData(elem, info)
+			if err != nil {
+				return nil, fmt.Errorf("dicom.DecodePixels: %v", err)
+			}
+			for _, data := range frames {
+				frame, err := decodeIntFrame(data, 0, samplesPerFrame, rleInfo, binary.LittleEndian)
+				if err != nil {
+					return nil, err
+				}
+				pixels.Frames = append(pixels.Frames, frame)
+			}
+			return pixels, nil
+		}
+		data, err := nativePixelData(elem)
+		if err != nil {
+			return nil, err
+		}
+		byteOrder, _, err := getTransferSyntax(ds)
+		if err != nil {
+			byteOrder = binary.LittleEndian
+		}
+		frameBits := info.storedSamplesPerFrame() * info.BitsAllocated
+		if need := (frameBits*info.NumberOfFrames + 7) / 8; len(data) < need {
+			return nil, fmt.Errorf("dicom.DecodePixels: pixel data has %d bytes, but %d frames of %dx%dx%d %d-bit samples need %d bytes",
+				len(data), info.NumberOfFrames, info.Rows, info.Columns, info.SamplesPerPixel, info.BitsAllocated, need)
+		}
+		for i := 0; i < info.NumberOfFrames; i++ {
+			frame, err := decodeIntFrame(data, i*frameBits, samplesPerFrame, info, byteOrder)
+			if err != nil {
+				return nil, err
+			}
+			pixels.Frames = append(pixels.Frames, frame)
+		}
+		return pixels, nil
+	}
+	for _, tag := range []dicomtag.Tag{dicomtag.FloatPixelData, dicomtag.DoubleFloatPixelData} {
+		elem, err := ds.FindElementByTag(tag)
+		if err != nil {
+			continue
+		}
+		if len(elem.Value) < samplesPerFrame*info.NumberOfFrames {
+			return nil, fmt.Errorf("dicom.DecodePixels: %v has %d values, but %d frames of %dx%dx%d samples need %d",
+				dicomtag.DebugString(tag), len(elem.Value), info.NumberOfFrames, info.Rows, info.Columns, info.SamplesPerPixel,
+				samplesPerFrame*info.NumberOfFrames)
+		}
+		for i := 0; i < info.NumberOfFrames; i++ {
+			frame, err := decodeFloatFrame(elem.Value[i*samplesPerFrame:(i+1)*samplesPerFrame], info)
+			if err != nil {
+				return nil, fmt.Errorf("dicom.DecodePixels: %v: %v", dicomtag.DebugString(tag), err)
+			}
+			pixels.Frames = append(pixels.Frames, frame)
+		}
+		return pixels, nil
+	}
+	return nil, fmt.Errorf("dicom.DecodePixels: pixel data not found")
+}
+
+// nativePixelData returns the raw bytes of a defined-length PixelData element.
+func nativePixelData(elem *Element) ([]byte, error) {
+	if len(elem.Value) != 1 {
+		return nil, fmt.Errorf("dicom.DecodePixels: PixelData has %d values (expect 1)", len(elem.Value))
+	}
+	switch v := elem.Value[0].(type) {
+	case PixelDataInfo:
+		if len(v.Frames) != 1 {
+			return nil, fmt.Errorf("dicom.DecodePixels: native PixelData has %d frames (expect 1)", len(v.Frames))
+		}
+		return v.Frames[0], nil
+	case []byte:
+		return v, nil
+	}
+	return nil, fmt.Errorf("dicom.DecodePixels: unexpected PixelData value %v", elem.Value[0])
+}
+
+// decodeIntFrame decodes the frame that starts at bit "bitOffset" of "data".
+func decodeIntFrame(data []byte, bitOffset, n int, info PixelInfo, byteOrder binary.ByteOrder) (interface{}, error) {
+	signed := info.PixelRepresentation == 1
+	shift := uint(info.HighBit + 1 - info.BitsStored)
+	mask := uint32(1)<<uint(info.BitsStored) - 1
+	if info.BitsStored == 32 {
+		mask = 0xffffffff
+	}
+	// sample returns the i'th stored value, masked and shifted.
+	var sample func(i int) uint32
+	data = data[bitOffset/8:]
+	switch info.BitsAllocated {
+	case 1:
+		bit := bitOffset % 8
+		sample = func(i int) uint32 {
+			b := bit + i
+			return uint32(data[b/8]>>uint(b%8)) & 1
+		}
+	case 8:
+		sample = func(i int) uint32 { return (uint32(data[i]) >> shift) & mask }
+	case 16:
+		sample = func(i int) uint32 { return (uint32(byteOrder.Uint16(data[2*i:])) >> shift) & mask }
+	case 32:
+		sample = func(i int) uint32 { return (byteOrder.Uint32(data[4*i:]) >> shift) & mask }
+	default:
+		return nil, fmt.Errorf("dicom.DecodePixels: BitsAllocated %d is not supported", info.BitsAllocated)
+	}
+	signBit := uint32(1) << uint(info.BitsStored-1)
+	// value returns the i'th sample, in color-by-pixel order, sign-extended.
+	value := func(i int) int64 {
+		v := sample(storedIndex(i, info))
+		if signed && v&signBit != 0 {
+			return int64(v) - int64(mask) - 1
+		}
+		return int64(v)
+	}
+	switch {
+	case info.BitsAllocated <= 8 && !signed:
+		frame := make([]uint8, n)
+		for i := range frame {
+			frame[i] = uint8(value(i))
+		}
+		return frame, nil
+	case info.BitsAllocated <= 16 && signed:
+		frame := make([]int16, n)
+		for i := range frame {
+			frame[i] = int16(value(i))
+		}
+		return frame, nil
+	case info.BitsAllocated == 16:
+		frame := make([]uint16, n)
+		for i := range frame {
+			frame[i] = uint16(value(i))
+		}
+		return frame, nil
+	case signed:
+		frame := make([]int32, n)
+		for i := range frame {
+			frame[i] = int32(value(i))
+		}
+		return frame, nil
+	default:
+		frame := make([]uint32, n)
+		for i := range frame {
+			frame[i] = uint32(value(i))
+		}
+		return frame, nil
+	}
+}
+
+// isYBR422 checks if the chroma of the image is subsampled horizontally, in
+// which case each pair of pixels is stored as Y1 Y2 Cb Cr.
+func (info PixelInfo) isYBR422() bool {
+	return info.PhotometricInterpretation == "YBR_FULL_422" || info.PhotometricInterpretation == "YBR_PARTIAL_422"
+}
+
+// storedSamplesPerFrame returns the number of samples that store a frame.
+func (info PixelInfo) storedSamplesPerFrame() int {
+	if info.isYBR422() && info.SamplesPerPixel == 3 {
+		return info.PixelsPerFrame() * 2
+	}
+	return info.PixelsPerFrame() * info.SamplesPerPixel
+}
+
+// storedIndex maps the index of a sample in color-by-pixel order to its index
+// in the frame as stored.
+func storedIndex(i int, info PixelInfo) int {
+	if info.SamplesPerPixel == 1 {
+		return i
+	}
+	pixel, sample := i/info.SamplesPerPixel, i%info.SamplesPerPixel
+	if info.isYBR422() && info.SamplesPerPixel == 3 {
+		pair := pixel / 2
+		if sample == 0 {
+			return pair*4 + pixel%2
+		}
+		return pair*4 + 1 + sample
+	}
+	if info.PlanarConfiguration == 0 {
+		return i
+	}
+	return sample*info.PixelsPerFrame() + pixel
+}
+
+// decodeFloatFrame converts the values of FloatPixelData or
+// DoubleFloatPixelData of one frame.
+func decodeFloatFrame(values []interface{}, info PixelInfo) (interface{}, error) {
+	switch values[0].(type) {
+	case float32:
+		frame := make([]float32, len(values))
+		for i := range frame {
+			v, ok := values[storedIndex(i, info)].(float32)
+			if !ok {
+				return nil, fmt.Errorf("expect float32, but found %v", values[storedIndex(i, info)])
+			}
+			frame[i] = v
+		}
+		return frame, nil
+	case float64:
+		frame := make([]float64, len(values))
+		for i := range frame {
+			v, ok := values[storedIndex(i, info)].(float64)
+			if !ok {
+				return nil, fmt.Errorf("expect float64, but found %v", values[storedIndex(i, info)])
+			}
+			frame[i] = v
+		}
+		return frame, nil
+	}
+	return nil, fmt.Errorf("expect float32 or float64, but found %v", values[0])
+}
@@ -0,0 +1,140 @@
+package dicom_test
+
+import (
+	"testing"
+
+	"github.com/msz-kp/go-dicom"
+	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newImage creates a dataset with the given Image Pixel module attributes and
+// native pixel data. Each attribute is a tag followed by its value, or a
+// []interface{} of its values.
+func newImage(pixelData []byte, attrs ...interface{}) *dicom.DataSet {
+	ds := &dicom.DataSet{}
+	for i := 0; i < len(attrs); i += 2 {
+		values, ok := attrs[i+1].([]interface{})
+		if !ok {
+			values = []interface{}{attrs[i+1]}
+		}
+		ds.Set(dicom.MustNewElement(attrs[i].(dicomtag.Tag), values...))
+	}
+	ds.Set(&dicom.Element{
+		Tag:   dicomtag.PixelData,
+		VR:    "OW",
+		Value: []interface{}{dicom.PixelDataInfo{Frames: [][]byte{pixelData}}},
+	})
+	return ds
+}
+
+func TestDecodePixels(t *testing.T) {
+	ds := mustReadFile(t, "examples/CT-MONO2-16-ort.dcm", dicom.ReadOptions{})
+	pixels, err := dicom.DecodePixels(ds)
+	require.NoError(t, err)
+	assert.Equal(t, 512, pixels.Rows)
+	assert.Equal(t, 512, pixels.Columns)
+	assert.Equal(t, "MONOCHROME2", pixels.PhotometricInterpretation)
+	require.Len(t, pixels.Frames, 1)
+	frame := pixels.Frames[0].([]int16)
+	assert.Len(t, frame, 512*512)
+	assert.Equal(t, int16(-2000), frame[0])
+}
+
+func TestDecodePixelsMasked(t *testing.T) {
+	// 12 bits stored in bits 1..12, with garbage in the other bits.
+	ds := newImage([]byte{0x03, 0xe0, 0xfe, 0x1f},
+		dicomtag.Rows, uint16(1), dicomtag.Columns, uint16(2),
+		dicomtag.BitsAllocated, uint16(16), dicomtag.BitsStored, uint16(12), dicomtag.HighBit, uint16(12),
+		dicomtag.PixelRepresentation, uint16(0))
+	pixels, err := dicom.DecodePixels(ds)
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{0x001, 0xfff}, pixels.Frames[0])
+
+	// The same, but signed.
+	ds.Set(dicom.MustNewElement(dicomtag.PixelRepresentation, uint16(1)))
+	pixels, err = dicom.DecodePixels(ds)
+	require.NoError(t, err)
+	assert.Equal(t, []int16{1, -1}, pixels.Frames[0])
+}
+
+func TestDecodePixelsBitDepths(t *testing.T) {
+	tests := []struct {
+		bits, signed int
+		data         []byte
+		want         interface{}
+	}{
+		{1, 0, []byte{0x0d}, []uint8{1, 0, 1, 1}},
+		{8, 0, []byte{0, 1, 0x7f, 0xff}, []uint8{0, 1, 0x7f, 0xff}},
+		{8, 1, []byte{0, 1, 0x7f, 0xff}, []int16{0, 1, 0x7f, -1}},
+		{16, 0, []byte{0, 0, 1, 0, 0xff, 0x7f, 0xff, 0xff}, []uint16{0, 1, 0x7fff, 0xffff}},
+		{16, 1, []byte{0, 0, 1, 0, 0xff, 0x7f, 0xff, 0xff}, []int16{0, 1, 0x7fff, -1}},
+		{32, 0, []byte{0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0x80, 0xff, 0xff, 0xff, 0xff}, []uint32{0, 1, 0x80000000, 0xffffffff}},
+		{32, 1, []byte{0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0x80, 0xff, 0xff, 0xff, 0xff}, []int32{0, 1, -0x80000000, -1}},
+	}
+	for _, test := range tests {
+		ds := newImage(test.data,
+			dicomtag.Rows, uint16(2), dicomtag.Columns, uint16(2),
+			dicomtag.BitsAllocated, uint16(test.bits), dicomtag.BitsStored, uint16(test.bits),
+			dicomtag.HighBit, uint16(test.bits-1), dicomtag.PixelRepresentation, uint16(test.signed))
+		pixels, err := dicom.DecodePixels(ds)
+		require.NoError(t, err, "bits %d", test.bits)
+		assert.Equal(t, test.want, pixels.Frames[0], "bits %d signed %d", test.bits, test.signed)
+	}
+}
+
+func TestDecodePixelsMultiFrame(t *testing.T) {
+	// Two 1x3 frames of single-bit pixels, packed across the frame boundary.
+	ds := newImage([]byte{0x2d},
+		dicomtag.Rows, uint16(1), dicomtag.Columns, uint16(3),
+		dicomtag.BitsAllocated, uint16(1), dicomtag.NumberOfFrames, "2")
+	pixels, err := dicom.DecodePixels(ds)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{[]uint8{1, 0, 1}, []uint8{1, 0, 1}}, pixels.Frames)
+
+	// Two 1x2 RGB frames, stored color-by-plane.
+	ds = newImage([]byte{1, 2, 3, 4, 5, 6, 11, 12, 13, 14, 15, 16},
+		dicomtag.Rows, uint16(1), dicomtag.Columns, uint16(2), dicomtag.BitsAllocated, uint16(8),
+		dicomtag.SamplesPerPixel, uint16(3), dicomtag.PlanarConfiguration, uint16(1), dicomtag.NumberOfFrames, "2")
+	pixels, err = dicom.DecodePixels(ds)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{[]uint8{1, 3, 5, 2, 4, 6}, []uint8{11, 13, 15, 12, 14, 16}}, pixels.Frames)
+
+	// The pixel data is too short for three frames.
+	ds.Set(dicom.MustNewElement(dicomtag.NumberOfFrames, "3"))
+	_, err = dicom.DecodePixels(ds)
+	assert.Error(t, err)
+}
+
+func TestDecodeFloatPixels(t *testing.T) {
+	ds := &dicom.DataSet{}
+	ds.Set(dicom.MustNewElement(dicomtag.Rows, uint16(1)))
+	ds.Set(dicom.MustNewElement(dicomtag.Columns, uint16(2)))
+	ds.Set(dicom.MustNewElement(dicomtag.FloatPixelData, float32(0.5), float32(-1)))
+	pixels, err := dicom.DecodePixels(ds)
+	require.NoError(t, err)
+	assert.Equal(t, 32, pixels.BitsAllocated)
+	assert.Equal(t, []interface{}{[]float32{0.5, -1}}, pixels.Frames)
+
+	ds.Remove(dicomtag.FloatPixelData)
+	ds.Set(dicom.MustNewElement(dicomtag.DoubleFloatPixelData, 0.5, -1.0))
+	pixels, err = dicom.DecodePixels(ds)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{[]float64{0.5, -1}}, pixels.Frames)
+}
+
+func TestDecodePixelsErrors(t *testing.T) {
+	_, err := dicom.DecodePixels(&dicom.DataSet{})
+	assert.Error(t, err)
+
+	ds := mustReadFile(t, "examples/IM-0001-0001.dcm", dicom.ReadOptions{})
+	_, err = dicom.DecodePixels(ds)
+	assert.Error(t, err, "encapsulated pixel data")
+
+	ds = newImage([]byte{0, 0},
+		dicomtag.Rows, uint16(1), dicomtag.Columns, uint16(1),
+		dicomtag.BitsAllocated, uint16(16), dicomtag.BitsStored, uint16(17))
+	_, err = dicom.DecodePixels(ds)
+	assert.Error(t, err)
+}
@@ -0,0 +1,432 @@
+package dicom
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/msz-kp/go-dicom/dicomio"
+	"github.com/msz-kp/go-dicom/dicomtag"
+)
+
+// RenderOptions controls how Render converts a frame into an image. The zero
+// value renders the first frame using the VOI transformation found in the
+// dataset.
+type RenderOptions struct {
+	// Frame is the index of the frame to render.
+	Frame int
+	// If WindowWidth > 0, WindowCenter and WindowWidth override the window
+	// found in the dataset.
+	WindowCenter, WindowWidth float64
+	// VOILUTFunction overrides the VOILUTFunction found in the dataset. One
+	// of "LINEAR", "LINEAR_EXACT" and "SIGMOID".
+	VOILUTFunction string
+	// WindowIndex selects one of the windows, or one of the items of
+	// VOILUTSequence, when the dataset has more than one.
+	WindowIndex int
+	// Gray16 makes Render produce an *image.Gray16, instead of an
+	// *image.Gray, for a grayscale image.
+	Gray16 bool
+}
+
+// Render decodes the pixel data of the dataset and renders one frame of it.
+// See RenderPixels.
+func Render(ds *DataSet, opts RenderOptions) (image.Image, error) {
+	pixels, err := DecodePixels(ds)
+	if err != nil {
+		return nil, err
+	}
+	return RenderPixels(pixels, ds, opts)
+}
+
+// RenderPixels renders one frame of the pixels decoded from "ds". Use it
+// instead of Render when rendering many frames of the same dataset.
+//
+// For a grayscale image, i.e., MONOCHROME1 or MONOCHROME2, it applies the
+// grayscale pipeline of P3.4 C.11:
+//
+//   - The modality LUT, i.e., the ModalityLUTSequence, or RescaleSlope and
+//     RescaleIntercept.
+//   - The VOI LUT, i.e., WindowCenter and WindowWidth with VOILUTFunction, or
+//     the VOILUTSequence. Without either, the window spans the range of the
+//     values in the frame.
+//   - Inversion, for MONOCHROME1.
+//
+// The result is an *image.Gray, or an *image.Gray16 if opts.Gray16 is set. A
+// color image is converted to RGB, as ConvertToRGB does, and then to an
+// *image.RGBA.
+func RenderPixels(pixels *Pixels, ds *DataSet, opts RenderOptions) (image.Image, error) {
+	if opts.Frame < 0 || opts.Frame >= len(pixels.Frames) {
+		return nil, fmt.Errorf("dicom.Render: frame %d out of range; the image has %d frames", opts.Frame, len(pixels.Frames))
+	}
+	frame := pixels.Frames[opts.Frame]
+	switch pi := pixels.PhotometricInterpretation; {
+	case pi == "MONOCHROME1" || pi == "MONOCHROME2" || (pi == "" && pixels.SamplesPerPixel == 1):
+		return renderGray(pixels, frame, ds, opts)
+	default:
+		info, convert, err := newRGBConverter(pixels, ds)
+		if err != nil {
+			return nil, err
+		}
+		rgb, err := convert(frame)
+		if err != nil {
+			return nil, err
+		}
+		return renderRGB(&Pixels{PixelInfo: info}, rgb)
+	}
+}
+
+func renderGray(pixels *Pixels, frame interface{}, ds *DataSet, opts RenderOptions) (image.Image, error) {
+	if pixels.SamplesPerPixel != 1 {
+		return nil, fmt.Errorf("dicom.Render: grayscale image with %d samples per pixel", pixels.SamplesPerPixel)
+	}
+	values, err := frameValues(frame)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyModalityLUT(values, pixels, ds); err != nil {
+		return nil, err
+	}
+	voi, err := newVOIFunc(values, pixels, ds, opts)
+	if err != nil {
+		return nil, err
+	}
+	invert := pixels.PhotometricInterpretation == "MONOCHROME1"
+	rect := image.Rect(0, 0, pixels.Columns, pixels.Rows)
+	var img image.Image
+	var set func(i int, y float64)
+	if opts.Gray16 {
+		gray := image.NewGray16(rect)
+		set = func(i int, y float64) {
+			v := uint16(math.Round(y * 0xffff))
+			gray.Pix[2*i], gray.Pix[2*i+1] = uint8(v>>8), uint8(v)
+		}
+		img = gray
+	} else {
+		gray := image.NewGray(rect)
+		set = func(i int, y float64) { gray.Pix[i] = uint8(math.Round(y * 0xff)) }
+		img = gray
+	}
+	for i, x := range values {
+		y := voi(x)
+		if invert {
+			y = 1 - y
+		}
+		set(i, y)
+	}
+	return img, nil
+}
+
+func renderRGB(pixels *Pixels, frame interface{}) (image.Image, error) {
+	if pixels.SamplesPerPixel != 3 {
+		return nil, fmt.Errorf("dicom.Render: RGB image with %d samples per pixel", pixels.SamplesPerPixel)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, pixels.Columns, pixels.Rows))
+	switch frame := frame.(type) {
+	case []uint8:
+		for i := 0; i < pixels.PixelsPerFrame(); i++ {
+			img.Pix[4*i], img.Pix[4*i+1], img.Pix[4*i+2], img.Pix[4*i+3] = frame[3*i], frame[3*i+1], frame[3*i+2], 0xff
+		}
+	case []uint16:
+		max := float64(uint32(1)<<uint(pixels.BitsStored) - 1)
+		for i := 0; i < pixels.PixelsPerFrame(); i++ {
+			for j := 0; j < 3; j++ {
+				img.Pix[4*i+j] = uint8(math.Round(float64(frame[3*i+j]) * 0xff / max))
+			}
+			img.Pix[4*i+3] = 0xff
+		}
+	default:
+		return nil, fmt.Errorf("dicom.Render: RGB image with %T samples is not supported", frame)
+	}
+	return img, nil
+}
+
+// frameValues converts a frame returned by DecodePixels to float64s.
+func frameValues(frame interface{}) ([]float64, error) {
+	var values []float64
+	switch frame := frame.(type) {
+	case []uint8:
+		values = make([]float64, len(frame))
+		for i, v := range frame {
+			values[i] = float64(v)
+		}
+	case []uint16:
+		values = make([]float64, len(frame))
+		for i, v := range frame {
+			values[i] = float64(v)
+		}
+	case []int16:
+		values = make([]float64, len(frame))
+		for i, v := range frame {
+			values[i] = float64(v)
+		}
+	case []uint32:
+		values = make([]float64, len(frame))
+		for i, v := range frame {
+			values[i] = float64(v)
+		}
+	case []int32:
+		values = make([]float64, len(frame))
+		for i, v := range frame {
+			values[i] = float64(v)
+		}
+	case []float32:
+		values = make([]float64, len(frame))
+		for i, v := range frame {
+			values[i] = float64(v)
+		}
+	case []float64:
+		values = append([]float64(nil), frame...)
+	default:
+		return nil, fmt.Errorf("dicom.Render: unsupported frame type %T", frame)
+	}
+	return values, nil
+}
+
+// lut is a lookup table found in ModalityLUTSequence or VOILUTSequence.
+// P3.3 C.11.1.1.
+type lut struct {
+	// The input value mapped to data[0].
+	first int
+	// Bits per entry.
+	bits int
+	data []uint16
+}
+
+// newLUT reads the LUTDescriptor and LUTData of a LUT item. "signed" tells
+// whether the first input value is signed, i.e., PixelRepresentation==1.
+func newLUT(item *Element, signed bool) (*lut, error) {
+	itemDS := &DataSet{Elements: item.GetElements()}
+	desc, err := itemDS.FindElementByTag(dicomtag.LUTDescriptor)
+	if err != nil {
+		return nil, err
+	}
+	data, err := itemDS.FindElementByTag(dicomtag.LUTData)
+	if err != nil {
+		return nil, err
+	}
+	return parseLUT(desc, data, signed)
+}
+
+// parseLUT reads a LUT from its descriptor and data elements, e.g.,
+// LUTDescriptor and LUTData.
+func parseLUT(descElem, dataElem *Element, signed bool) (*lut, error) {
+	l, n, err := parseLUTDescriptor(descElem, signed)
+	if err != nil {
+		return nil, err
+	}
+	if l.data, err = lutData(dataElem, n, l.bits); err != nil {
+		return nil, err
+	}
+	if len(l.data) < n {
+		return nil, fmt.Errorf("%v has %d entries, but %v says %d",
+			dicomtag.DebugString(dataElem.Tag), len(l.data), dicomtag.DebugString(descElem.Tag), n)
+	}
+	l.data = l.data[:n]
+	return l, nil
+}
+
+// parseLUTDescriptor reads a LUT descriptor. It returns a LUT without data,
+// and the number of entries.
+func parseLUTDescriptor(descElem *Element, signed bool) (*lut, int, error) {
+	desc, err := descElem.GetInt64s()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(desc) != 3 {
+		return nil, 0, fmt.Errorf("%v has %d values (expect 3)", dicomtag.DebugString(descElem.Tag), len(desc))
+	}
+	l := &lut{first: int(desc[1]), bits: int(desc[2])}
+	if signed && l.first >= 0x8000 {
+		l.first -= 0x10000
+	}
+	if l.bits < 1 || l.bits > 16 {
+		return nil, 0, fmt.Errorf("%v: invalid bits per entry %d", dicomtag.DebugString(descElem.Tag), l.bits)
+	}
+	n := int(desc[0])
+	if n == 0 {
+		n = 1 << 16
+	}
+	return l, n, nil
+}
+
+// lutData returns the entries of LUT data, which is either US or OW. "n" and
+// "bits" are the number of entries and bits per entry found in the descriptor.
+func lutData(elem *Element, n, bits int) ([]uint16, error) {
+	if len(elem.Value) == 1 {
+		if b, ok := elem.Value[0].([]byte); ok {
+			if bits <= 8 && (len(b) == n || len(b) == n+1) {
+				// 8-bit entries packed into bytes, possibly padded.
+				data := make([]uint16, n)
+				for i := range data {
+					data[i] = uint16(b[i])
+				}
+				return data, nil
+			}
+			// OW values are stored in the native byte order.
+			data := make([]uint16, len(b)/2)
+			for i := range data {
+				data[i] = dicomio.NativeByteOrder.Uint16(b[2*i:])
+			}
+			return data, nil
+		}
+	}
+	return elem.GetUint16s()
+}
+
+// lookup returns the entry for "x", clamping it to the range of the table.
+func (l *lut) lookup(x float64) float64 {
+	i := int(math.Round(x)) - l.first
+	if i < 0 {
+		i = 0
+	} else if i >= len(l.data) {
+		i = len(l.data) - 1
+	}
+	return float64(l.data[i])
+}
+
+// applyModalityLUT converts the stored values into modality values, in place.
+// P3.3 C.11.1.
+func applyModalityLUT(values []float64, pixels *Pixels, ds *DataSet) error {
+	if seq, err := ds.FindElementByTag(dicomtag.ModalityLUTSequence); err == nil {
+		if items := seq.GetElements(); len(items) > 0 {
+			l, err := newLUT(items[0], pixels.PixelRepresentation == 1)
+			if err != nil {
+				return fmt.Errorf("dicom.Render: ModalityLUTSequence: %v", err)
+			}
+			for i, v := range values {
+				values[i] = l.lookup(v)
+			}
+			return nil
+		}
+	}
+	slope, intercept := 1.0, 0.0
+	if elem, err := ds.FindElementByTag(dicomtag.RescaleSlope); err == nil {
+		if slope, err = elem.GetFloat64(); err != nil {
+			return fmt.Errorf("dicom.Render: %v", err)
+		}
+	}
+	if elem, err := ds.FindElementByTag(dicomtag.RescaleIntercept); err == nil {
+		if intercept, err = elem.GetFloat64(); err != nil {
+			return fmt.Errorf("dicom.Render: %v", err)
+		}
+	}
+	if slope != 1 || intercept != 0 {
+		for i, v := range values {
+			values[i] = v*slope + intercept
+		}
+	}
+	return nil
+}
+
+// newVOIFunc returns the VOI LUT function, which maps a modality value to
+// [0,1]. "values" are the modality values of the frame. P3.3 C.11.2.
+func newVOIFunc(values []float64, pixels *Pixels, ds *DataSet, opts RenderOptions) (func(float64) float64, error) {
+	function := opts.VOILUTFunction
+	if function == "" {
+		if elem, err := ds.FindElementByTag(dicomtag.VOILUTFunction); err == nil {
+			function, _ = elem.GetString()
+		}
+	}
+	center, width := opts.WindowCenter, opts.WindowWidth
+	if width <= 0 {
+		centers, widths, err := datasetWindows(ds)
+		if err != nil {
+			return nil, err
+		}
+		if len(centers) > 0 {
+			i := opts.WindowIndex
+			if i < 0 || i >= len(centers) {
+				return nil, fmt.Errorf("dicom.Render: window %d out of range; the dataset has %d windows", i, len(centers))
+			}
+			center, width = centers[i], widths[i]
+		}
+	}
+	if width <= 0 {
+		if seq, err := ds.FindElementByTag(dicomtag.VOILUTSequence); err == nil {
+			if items := seq.GetElements(); len(items) > 0 {
+				i := opts.WindowIndex
+				if i < 0 || i >= len(items) {
+					return nil, fmt.Errorf("dicom.Render: VOI LUT %d out of range; the dataset has %d", i, len(items))
+				}
+				l, err := newLUT(items[i], pixels.PixelRepresentation == 1)
+				if err != nil {
+					return nil, fmt.Errorf("dicom.Render: VOILUTSequence: %v", err)
+				}
+				max := float64(uint32(1)<<uint(l.bits) - 1)
+				return func(x float64) float64 { return l.lookup(x) / max }, nil
+			}
+		}
+	}
+	if width <= 0 {
+		// No VOI transformation. Use the range of the values.
+		min, max := math.Inf(1), math.Inf(-1)
+		for _, v := range values {
+			min, max = math.Min(min, v), math.Max(max, v)
+		}
+		if len(values) == 0 || max == min {
+			return func(float64) float64 { return 0 }, nil
+		}
+		center, width, function = (min+max)/2, max-min, "LINEAR_EXACT"
+	}
+	return windowFunc(function, center, width)
+}
+
+// datasetWindows returns the WindowCenter and WindowWidth values of the
+// dataset.
+func datasetWindows(ds *DataSet) (centers, widths []float64, err error) {
+	centerElem, err := ds.FindElementByTag(dicomtag.WindowCenter)
+	if err != nil {
+		return nil, nil, nil
+	}
+	widthElem, err := ds.FindElementByTag(dicomtag.WindowWidth)
+	if err != nil {
+		return nil, nil, nil
+	}
+	if centers, err = centerElem.GetFloat64s(); err != nil {
+		return nil, nil, fmt.Errorf("dicom.Render: %v", err)
+	}
+	if widths, err = widthElem.GetFloat64s(); err != nil {
+		return nil, nil, fmt.Errorf("dicom.Render: %v", err)
+	}
+	if len(centers) != len(widths) {
+		return nil, nil, fmt.Errorf("dicom.Render: %d WindowCenter values, but %d WindowWidth values", len(centers), len(widths))
+	}
+	return centers, widths, nil
+}
+
+// windowFunc returns the VOI LUT function of P3.3 C.11.2.1.2 and C.11.2.1.3,
+// scaled to [0,1].
+func windowFunc(function string, center, width float64) (func(float64) float64, error) {
+	switch function {
+	case "", "LINEAR":
+		if width < 1 {
+			return nil, fmt.Errorf("dicom.Render: WindowWidth %v must be >= 1 for LINEAR", width)
+		}
+		lower, upper := center-0.5-(width-1)/2, center-0.5+(width-1)/2
+		return func(x float64) float64 {
+			switch {
+			case x <= lower:
+				return 0
+			case x > upper:
+				return 1
+			}
+			return (x-(center-0.5))/(width-1) + 0.5
+		}, nil
+	case "LINEAR_EXACT":
+		return func(x float64) float64 {
+			switch {
+			case x <= center-width/2:
+				return 0
+			case x > center+width/2:
+				return 1
+			}
+			return (x-center)/width + 0.5
+		}, nil
+	case "SIGMOID":
+		return func(x float64) float64 {
+			return 1 / (1 + math.Exp(-4*(x-center)/width))
+		}, nil
+	}
+	return nil, fmt.Errorf("dicom.Render: unknown VOILUTFunction '%s'", function)
+}
@@ -0,0 +1,108 @@
+package dicom_test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/msz-kp/go-dicom"
+	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGrayImage creates a 1xN MONOCHROME2 image of signed 16-bit pixels.
+func newGrayImage(values ...int16) *dicom.DataSet {
+	data := make([]byte, 2*len(values))
+	for i, v := range values {
+		data[2*i], data[2*i+1] = uint8(v), uint8(uint16(v)>>8)
+	}
+	return newImage(data,
+		dicomtag.PhotometricInterpretation, "MONOCHROME2",
+		dicomtag.Rows, uint16(1), dicomtag.Columns, uint16(len(values)),
+		dicomtag.BitsAllocated, uint16(16), dicomtag.BitsStored, uint16(16), dicomtag.HighBit, uint16(15),
+		dicomtag.PixelRepresentation, uint16(1))
+}
+
+func mustRenderGray(t *testing.T, ds *dicom.DataSet, opts dicom.RenderOptions) []uint8 {
+	img, err := dicom.Render(ds, opts)
+	require.NoError(t, err)
+	gray, ok := img.(*image.Gray)
+	require.True(t, ok, "%T", img)
+	return gray.Pix
+}
+
+func TestRenderWindow(t *testing.T) {
+	ds := newGrayImage(-1100, -1000, 0, 1000, 1100)
+	// Without a window, the range of the values is used.
+	assert.Equal(t, []uint8{0, 12, 128, 243, 255}, mustRenderGray(t, ds, dicom.RenderOptions{}))
+
+	ds.Set(dicom.MustNewElement(dicomtag.RescaleSlope, "1"))
+	ds.Set(dicom.MustNewElement(dicomtag.RescaleIntercept, "-1000"))
+	ds.Set(dicom.MustNewElement(dicomtag.WindowCenter, "0", "-1000"))
+	ds.Set(dicom.MustNewElement(dicomtag.WindowWidth, "2001", "400"))
+	// The modality values are -2100, -2000, -1000, 0, 100.
+	assert.Equal(t, []uint8{0, 0, 0, 128, 140}, mustRenderGray(t, ds, dicom.RenderOptions{}))
+	assert.Equal(t, []uint8{0, 0, 128, 255, 255}, mustRenderGray(t, ds, dicom.RenderOptions{WindowIndex: 1}))
+	assert.Equal(t, []uint8{0, 0, 128, 255, 255}, mustRenderGray(t, ds, dicom.RenderOptions{WindowCenter: -1000, WindowWidth: 400}))
+
+	ds.Set(dicom.MustNewElement(dicomtag.VOILUTFunction, "LINEAR_EXACT"))
+	assert.Equal(t, []uint8{0, 0, 128, 255, 255}, mustRenderGray(t, ds, dicom.RenderOptions{WindowIndex: 1}))
+	ds.Set(dicom.MustNewElement(dicomtag.VOILUTFunction, "SIGMOID"))
+	assert.Equal(t, []uint8{0, 0, 128, 255, 255}, mustRenderGray(t, ds, dicom.RenderOptions{WindowIndex: 1}))
+	assert.Equal(t, []uint8{14, 15, 21, 30, 31},
+		mustRenderGray(t, ds, dicom.RenderOptions{WindowCenter: 5000, WindowWidth: 10000}))
+
+	ds.Set(dicom.MustNewElement(dicomtag.PhotometricInterpretation, "MONOCHROME1"))
+	assert.Equal(t, []uint8{255, 255, 128, 0, 0}, mustRenderGray(t, ds, dicom.RenderOptions{WindowIndex: 1}))
+
+	_, err := dicom.Render(ds, dicom.RenderOptions{WindowIndex: 2})
+	assert.Error(t, err)
+	_, err = dicom.Render(ds, dicom.RenderOptions{Frame: 1})
+	assert.Error(t, err)
+
+	img, err := dicom.Render(ds, dicom.RenderOptions{WindowIndex: 1, VOILUTFunction: "LINEAR_EXACT", Gray16: true})
+	require.NoError(t, err)
+	gray16, ok := img.(*image.Gray16)
+	require.True(t, ok, "%T", img)
+	assert.Equal(t, uint16(0xffff), gray16.Gray16At(0, 0).Y)
+	assert.Equal(t, uint16(0), gray16.Gray16At(4, 0).Y)
+}
+
+func newLUTItem(first uint16, bits uint16, data ...uint16) *dicom.Element {
+	values := make([]interface{}, len(data))
+	for i, v := range data {
+		values[i] = v
+	}
+	return dicom.MustNewElement(dicomtag.Item,
+		dicom.MustNewElement(dicomtag.LUTDescriptor, uint16(len(data)), first, bits),
+		dicom.MustNewElement(dicomtag.LUTData, values...))
+}
+
+func TestRenderLUTs(t *testing.T) {
+	ds := newGrayImage(0, 1, 2, 3, 4)
+	// The modality LUT maps 1..3 to 10, 20, 30, and clamps the others.
+	ds.Set(dicom.MustNewElement(dicomtag.ModalityLUTSequence, newLUTItem(1, 16, 10, 20, 30)))
+	// The VOI LUT maps 10..30 to 0..255.
+	voi := make([]uint16, 21)
+	for i := range voi {
+		voi[i] = uint16(i * 255 / 20)
+	}
+	ds.Set(dicom.MustNewElement(dicomtag.VOILUTSequence, newLUTItem(10, 8, voi...)))
+	assert.Equal(t, []uint8{0, 0, 127, 255, 255}, mustRenderGray(t, ds, dicom.RenderOptions{}))
+
+	// A window takes precedence over the VOI LUT.
+	ds.Set(dicom.MustNewElement(dicomtag.WindowCenter, "20"))
+	ds.Set(dicom.MustNewElement(dicomtag.WindowWidth, "1"))
+	assert.Equal(t, []uint8{0, 0, 255, 255, 255}, mustRenderGray(t, ds, dicom.RenderOptions{}))
+}
+
+func TestRenderRGB(t *testing.T) {
+	ds := newImage([]byte{1, 2, 3, 4, 5, 6},
+		dicomtag.PhotometricInterpretation, "RGB", dicomtag.SamplesPerPixel, uint16(3),
+		dicomtag.Rows, uint16(1), dicomtag.Columns, uint16(2), dicomtag.BitsAllocated, uint16(8))
+	img, err := dicom.Render(ds, dicom.RenderOptions{})
+	require.NoError(t, err)
+	rgba, ok := img.(*image.RGBA)
+	require.True(t, ok, "%T", img)
+	assert.Equal(t, []uint8{1, 2, 3, 255, 4, 5, 6, 255}, rgba.Pix)
+}
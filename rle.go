@@ -0,0 +1,242 @@
+package dicom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/msz-kp/go-dicom/dicomtag"
+)
+
+// RLE Lossless compresses each frame separately. A frame starts with a header
+// that holds the number of segments and their offsets, and each segment holds
+// one byte of one sample of every pixel, compressed with the PackBits
+// algorithm. The segments are ordered by sample, and within a sample, from the
+// most significant byte. P3.5 Annex G.
+
+const (
+	// rleHeaderSize is the size of the header that precedes the segments.
+	rleHeaderSize = 64
+	// rleMaxSegments is the maximum number of segments in a frame.
+	rleMaxSegments = 15
+)
+
+// rleSegments returns the number of segments that encode a frame, and the
+// number of bytes in each sample.
+func rleSegments(info PixelInfo) (segments, bytesPerSample int, err error) {
+	if info.BitsAllocated%8 != 0 || info.BitsAllocated == 0 {
+		return 0, 0, fmt.Errorf("RLE Lossless with BitsAllocated %d is not supported", info.BitsAllocated)
+	}
+	if info.isYBR422() {
+		return 0, 0, fmt.Errorf("RLE Lossless with photometric interpretation %s is not supported", info.PhotometricInterpretation)
+	}
+	bytesPerSample = info.BitsAllocated / 8
+	segments = info.SamplesPerPixel * bytesPerSample
+	if segments > rleMaxSegments {
+		return 0, 0, fmt.Errorf("RLE Lossless can't encode %d samples of %d bits in %d segments",
+			info.SamplesPerPixel, info.BitsAllocated, rleMaxSegments)
+	}
+	return segments, bytesPerSample, nil
+}
+
+// decodeRLEFrame decompresses one frame of RLE Lossless pixel data. The
+// result is the frame in the native little endian format, with the samples
+// stored color-by-plane, i.e., as if PlanarConfiguration were 1.
+func decodeRLEFrame(data []byte, info PixelInfo) ([]byte, error) {
+	numSegments, bytesPerSample, err := rleSegments(info)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < rleHeaderSize {
+		return nil, fmt.Errorf("RLE frame has %d bytes, which is shorter than the header", len(data))
+	}
+	if n := int(binary.LittleEndian.Uint32(data)); n != numSegments {
+		return nil, fmt.Errorf("RLE frame has %d segments, but %d samples of %d bits need %d",
+			n, info.SamplesPerPixel, info.BitsAllocated, numSegments)
+	}
+	numPixels := info.PixelsPerFrame()
+	out := make([]byte, numPixels*numSegments)
+	for s := 0; s < numSegments; s++ {
+		start := int(binary.LittleEndian.Uint32(data[4+4*s:]))
+		end := len(data)
+		if s+1 < numSegments {
+			end = int(binary.LittleEndian.Uint32(data[8+4*s:]))
+		}
+		if start < rleHeaderSize || start > end || end > len(data) {
+			return nil, fmt.Errorf("RLE segment %d has invalid range [%d, %d) in a %d-byte frame", s, start, end, len(data))
+		}
+		segment, err := unpackBits(data[start:end], numPixels)
+		if err != nil {
+			return nil, fmt.Errorf("RLE segment %d: %v", s, err)
+		}
+		sample, b := s/bytesPerSample, bytesPerSample-1-s%bytesPerSample
+		for p, v := range segment {
+			out[(sample*numPixels+p)*bytesPerSample+b] = v
+		}
+	}
+	return out, nil
+}
+
+// unpackBits decompresses a PackBits segment that holds "n" bytes. Extra
+// bytes, such as the padding at the end of a segment, are ignored.
+func unpackBits(data []byte, n int) ([]byte, error) {
+	out := make([]byte, 0, n)
+	for i := 0; len(out) < n; {
+		if i >= len(data) {
+			return nil, fmt.Errorf("segment decodes to %d bytes (expect %d)", len(out), n)
+		}
+		header := int(int8(data[i]))
+		i++
+		switch {
+		case header >= 0:
+			if i+header+1 > len(data) {
+				return nil, fmt.Errorf("literal run at byte %d is truncated", i-1)
+			}
+			out = append(out, data[i:i+header+1]...)
+			i += header + 1
+		case header > -128:
+			if i >= len(data) {
+				return nil, fmt.Errorf("replicate run at byte %d is truncated", i-1)
+			}
+			for j := 0; j < 1-header; j++ {
+				out = append(out, data[i])
+			}
+			i++
+		}
+	}
+	if len(out) > n {
+		return nil, fmt.Errorf("segment decodes to more than %d bytes", n)
+	}
+	return out, nil
+}
+
+// encodeRLEFrame compresses one frame of native little endian pixel data, as
+// laid out by the given PixelInfo, into RLE Lossless.
+func encodeRLEFrame(frame []byte, info PixelInfo) ([]byte, error) {
+	numSegments, bytesPerSample, err := rleSegments(info)
+	if err != nil {
+		return nil, err
+	}
+	numPixels := info.PixelsPerFrame()
+	if need := numPixels * numSegments; len(frame) < need {
+		return nil, fmt.Errorf("frame has %d bytes (expect %d)", len(frame), need)
+	}
+	out := make([]byte, rleHeaderSize)
+	binary.LittleEndian.PutUint32(out, uint32(numSegments))
+	segment := make([]byte, numPixels)
+	for s := 0; s < numSegments; s++ {
+		binary.LittleEndian.PutUint32(out[4+4*s:], uint32(len(out)))
+		sample, b := s/bytesPerSample, bytesPerSample-1-s%bytesPerSample
+		for p := range segment {
+			segment[p] = frame[storedIndex(p*info.SamplesPerPixel+sample, info)*bytesPerSample+b]
+		}
+		// Each row is compressed separately. P3.5 G.3.1.
+		for row := 0; row < info.Rows; row++ {
+			out = packBits(out, segment[row*info.Columns:(row+1)*info.Columns])
+		}
+		if len(out)%2 != 0 {
+			out = append(out, 0)
+		}
+	}
+	return out, nil
+}
+
+// packBits appends the PackBits compression of "data" to "out".
+func packBits(out, data []byte) []byte {
+	// runLength returns the number of bytes equal to data[i] that start at i.
+	runLength := func(i int) int {
+		n := 1
+		for i+n < len(data) && n < 128 && data[i+n] == data[i] {
+			n++
+		}
+		return n
+	}
+	for i := 0; i < len(data); {
+		if n := runLength(i); n >= 2 {
+			out = append(out, byte(1-n), data[i])
+			i += n
+			continue
+		}
+		// A literal run lasts until a replicate run of three or more bytes
+		// starts. A run of two isn't worth breaking the literal run for.
+		j := i + 1
+		for j < len(data) && j-i < 128 && runLength(j) < 3 {
+			j++
+		}
+		out = append(out, byte(j-i-1))
+		out = append(out, data[i:j]...)
+		i = j
+	}
+	return out
+}
+
+// decodeRLEPixelData decompresses the frames of an encapsulated PixelData
+// element in RLE Lossless. It returns the frames in the native format, and the
+// PixelInfo that describes their layout.
+func decodeRLEPixelData(elem *Element, info PixelInfo) ([][]byte, PixelInfo, error) {
+	if len(elem.Value) != 1 {
+		return nil, info, fmt.Errorf("PixelData has %d values (expect 1)", len(elem.Value))
+	}
+	image, ok := elem.Value[0].(PixelDataInfo)
+	if !ok {
+		return nil, info, fmt.Errorf("unexpected PixelData value %v", elem.Value[0])
+	}
+	if len(image.Frames) < info.NumberOfFrames {
+		return nil, info, fmt.Errorf("PixelData has %d frames (expect %d)", len(image.Frames), info.NumberOfFrames)
+	}
+	var frames [][]byte
+	for i := 0; i < info.NumberOfFrames; i++ {
+		frame, err := decodeRLEFrame(image.Frames[i], info)
+		if err != nil {
+			return nil, info, fmt.Errorf("frame %d: %v", i, err)
+		}
+		frames = append(frames, frame)
+	}
+	info.PlanarConfiguration = 1
+	return frames, info, nil
+}
+
+// encodeRLEPixelData compresses the native PixelData element of "ds" into RLE
+// Lossless. The native pixel data must be little endian. It returns an
+// encapsulated PixelData element with one fragment per frame, and the offsets
+// of the frames.
+func encodeRLEPixelData(ds *DataSet, elem *Element) (*Element, error) {
+	info, err := NewPixelInfo(ds)
+	if err != nil {
+		return nil, fmt.Errorf("dicom.WriteDataSet: %v", err)
+	}
+	if err := info.validate(); err != nil {
+		return nil, err
+	}
+	data, err := nativePixelData(elem)
+	if err != nil {
+		return nil, err
+	}
+	frameSize := info.storedSamplesPerFrame() * info.BitsAllocated / 8
+	if need := frameSize * info.NumberOfFrames; len(data) < need {
+		return nil, fmt.Errorf("dicom.WriteDataSet: pixel data has %d bytes, but %d frames need %d bytes",
+			len(data), info.NumberOfFrames, need)
+	}
+	var image PixelDataInfo
+	for i := 0; i < info.NumberOfFrames; i++ {
+		frame, err := encodeRLEFrame(data[i*frameSize:(i+1)*frameSize], info)
+		if err != nil {
+			return nil, fmt.Errorf("dicom.WriteDataSet: %v: frame %d: %v", dicomtag.DebugString(elem.Tag), i, err)
+		}
+		image.Frames = append(image.Frames, frame)
+	}
+	for _, offset := range fragmentOffsets(image.Frames) {
+		if offset > math.MaxUint32 {
+			// The basic offset table can't hold the offsets.
+			image.Offsets = nil
+			break
+		}
+		image.Offsets = append(image.Offsets, uint32(offset))
+	}
+	return &Element{
+		Tag:             elem.Tag,
+		VR:              "OB",
+		UndefinedLength: true,
+		Value:           []interface{}{image},
+	}, nil
+}
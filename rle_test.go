@@ -0,0 +1,130 @@
+package dicom_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/msz-kp/go-dicom"
+	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/msz-kp/go-dicom/dicomuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setRLEPixelData replaces the pixel data of "ds" with the given RLE frames.
+func setRLEPixelData(ds *dicom.DataSet, frames ...[]byte) {
+	ds.Set(dicom.MustNewElement(dicomtag.TransferSyntaxUID, dicomuid.RLELossless))
+	ds.Set(&dicom.Element{
+		Tag:             dicomtag.PixelData,
+		VR:              "OB",
+		UndefinedLength: true,
+		Value:           []interface{}{dicom.PixelDataInfo{Frames: frames}},
+	})
+}
+
+// newRLEFrame creates an RLE frame from the given segments.
+func newRLEFrame(segments ...[]byte) []byte {
+	frame := make([]byte, 64)
+	binary.LittleEndian.PutUint32(frame, uint32(len(segments)))
+	for i, segment := range segments {
+		binary.LittleEndian.PutUint32(frame[4+4*i:], uint32(len(frame)))
+		frame = append(frame, segment...)
+	}
+	return frame
+}
+
+func TestDecodeRLE(t *testing.T) {
+	ds := newImage(nil,
+		dicomtag.Rows, uint16(1), dicomtag.Columns, uint16(4),
+		dicomtag.BitsAllocated, uint16(16), dicomtag.PixelRepresentation, uint16(1))
+	setRLEPixelData(ds, newRLEFrame(
+		// High bytes: 0x00 repeated three times, then a literal 0xff.
+		[]byte{0xfe, 0x00, 0x00, 0xff},
+		// Low bytes: a literal run of four, and a padding byte.
+		[]byte{0x03, 0x01, 0x02, 0x03, 0xff, 0x00}))
+	pixels, err := dicom.DecodePixels(ds)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{[]int16{1, 2, 3, -1}}, pixels.Frames)
+
+	// The segments are stored color-by-plane, regardless of
+	// PlanarConfiguration.
+	ds = newColorImage("RGB", 2, nil)
+	setRLEPixelData(ds, newRLEFrame([]byte{0xff, 1}, []byte{0x01, 2, 3}, []byte{0xff, 4}))
+	pixels, err = dicom.DecodePixels(ds)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{[]uint8{1, 2, 4, 1, 3, 4}}, pixels.Frames)
+
+	// Truncated segment.
+	setRLEPixelData(ds, newRLEFrame([]byte{0xff, 1}, []byte{0x01, 2}, []byte{0xff, 4}))
+	_, err = dicom.DecodePixels(ds)
+	assert.Error(t, err)
+
+	// Wrong number of segments.
+	setRLEPixelData(ds, newRLEFrame([]byte{0xff, 1}))
+	_, err = dicom.DecodePixels(ds)
+	assert.Error(t, err)
+}
+
+// writeAndRead writes the dataset in the given transfer syntax and reads it
+// back.
+func writeAndRead(t *testing.T, ds *dicom.DataSet, transferSyntaxUID string) *dicom.DataSet {
+	ds.Set(dicom.MustNewElement(dicomtag.TransferSyntaxUID, transferSyntaxUID))
+	ds.Set(dicom.MustNewElement(dicomtag.MediaStorageSOPClassUID, "1.2.840.10008.5.1.4.1.1.7"))
+	ds.Set(dicom.MustNewElement(dicomtag.MediaStorageSOPInstanceUID, "1.2.3.4.5.6.7"))
+	var buf bytes.Buffer
+	require.NoError(t, dicom.WriteDataSet(&buf, ds))
+	ds2, err := dicom.ReadDataSetInBytes(buf.Bytes(), dicom.ReadOptions{})
+	require.NoError(t, err)
+	return ds2
+}
+
+func TestRLERoundTrip(t *testing.T) {
+	data := make([]byte, 2*3*4*2)
+	for i := range data {
+		// Runs, and bytes that differ from their neighbors.
+		data[i] = byte(i / 5 * 7)
+	}
+	tests := []*dicom.DataSet{
+		newImage(data,
+			dicomtag.Rows, uint16(3), dicomtag.Columns, uint16(4), dicomtag.NumberOfFrames, "2",
+			dicomtag.BitsAllocated, uint16(16), dicomtag.BitsStored, uint16(12), dicomtag.HighBit, uint16(11),
+			dicomtag.PixelRepresentation, uint16(1)),
+		newImage(data,
+			dicomtag.Rows, uint16(2), dicomtag.Columns, uint16(4), dicomtag.BitsAllocated, uint16(8),
+			dicomtag.SamplesPerPixel, uint16(3), dicomtag.PhotometricInterpretation, "RGB",
+			dicomtag.PlanarConfiguration, uint16(1), dicomtag.NumberOfFrames, "2"),
+		newColorImage("RGB", 16, data[:48]),
+	}
+	for _, ds := range tests {
+		want, err := dicom.DecodePixels(ds)
+		require.NoError(t, err)
+		ds2 := writeAndRead(t, ds, dicomuid.RLELossless)
+		elem, err := ds2.FindElementByTag(dicomtag.PixelData)
+		require.NoError(t, err)
+		assert.True(t, elem.UndefinedLength)
+		image := elem.Value[0].(dicom.PixelDataInfo)
+		require.Len(t, image.Frames, want.NumberOfFrames)
+		if want.NumberOfFrames > 1 {
+			assert.Equal(t, []uint32{0, uint32(8 + len(image.Frames[0]))}, image.Offsets)
+		}
+		got, err := dicom.DecodePixels(ds2)
+		require.NoError(t, err)
+		assert.Equal(t, want.Frames, got.Frames)
+
+		// The original dataset isn't modified.
+		elem, err = ds.FindElementByTag(dicomtag.PixelData)
+		require.NoError(t, err)
+		assert.False(t, elem.UndefinedLength)
+	}
+
+	// RLE Lossless can't encode 1-bit pixels.
+	ds := newImage([]byte{0xff}, dicomtag.Rows, uint16(1), dicomtag.Columns, uint16(8), dicomtag.BitsAllocated, uint16(1))
+	ds.Set(dicom.MustNewElement(dicomtag.TransferSyntaxUID, dicomuid.RLELossless))
+	ds.Set(dicom.MustNewElement(dicomtag.MediaStorageSOPClassUID, "1.2.840.10008.5.1.4.1.1.7"))
+	ds.Set(dicom.MustNewElement(dicomtag.MediaStorageSOPInstanceUID, "1.2.3.4.5.6.7"))
+	var buf bytes.Buffer
+	assert.Error(t, dicom.WriteDataSet(&buf, ds))
+	// Nothing is written on error.
+	assert.Zero(t, buf.Len())
+}
@@ -0,0 +1,72 @@
+package dicom
+
+import (
+	"errors"
+
+	"github.com/msz-kp/go-dicom/dicomtag"
+)
+
+// SkipSubtree is used as a return value from a WalkFunc to indicate that the
+// items of the sequence, or the elements of the item, named in the call are to
+// be skipped. It is not returned as an error by Walk.
+var SkipSubtree = errors.New("skip this subtree")
+
+// WalkFunc is the type of the function called by DataSet.Walk for each element.
+//
+// "path" locates "elem" from the root of the dataset. For an element, the last
+// step has Index==NoItem; for an item, i.e., elem.Tag==dicomtag.Item, the last
+// step names the enclosing sequence and has the item's index. Path(path)
+// converts it to a Path. The slice is not reused, so the function may retain
+// it.
+//
+// If the function returns SkipSubtree for a sequence or an item, Walk skips
+// its contents. If it returns any other non-nil error, Walk stops and returns
+// that error.
+type WalkFunc func(path []PathStep, elem *Element) error
+
+// Walk calls "fn" for each element in the dataset in document order, i.e.,
+// a sequence is visited before its items, and an item before its elements. A
+// UN element of undefined length is treated as a sequence, since that's how
+// it's read.
+func (f *DataSet) Walk(fn WalkFunc) error {
+	return walkElements(f.Elements, nil, fn)
+}
+
+func walkElements(elems []*Element, path []PathStep, fn WalkFunc) error {
+	for _, elem := range elems {
+		elemPath := appendPathStep(path, PathStep{Tag: elem.Tag, Index: NoItem})
+		if err := fn(elemPath, elem); err != nil {
+			if err == SkipSubtree {
+				continue
+			}
+			return err
+		}
+		if !elem.isSequence() {
+			continue
+		}
+		for i, v := range elem.Value {
+			item, ok := v.(*Element)
+			if !ok || item.Tag != dicomtag.Item {
+				continue
+			}
+			itemPath := appendPathStep(path, PathStep{Tag: elem.Tag, Index: i})
+			if err := fn(itemPath, item); err != nil {
+				if err == SkipSubtree {
+					continue
+				}
+				return err
+			}
+			if err := walkElements(item.GetElements(), itemPath, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func appendPathStep(path []PathStep, step PathStep) []PathStep {
+	p := make([]PathStep, len(path)+1)
+	copy(p, path)
+	p[len(path)] = step
+	return p
+}
@@ -0,0 +1,120 @@
+package dicom_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/msz-kp/go-dicom"
+	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalk(t *testing.T) {
+	ds := &dicom.DataSet{Elements: []*dicom.Element{
+		dicom.MustNewElement(dicomtag.ReferencedSeriesSequence,
+			newReferencedSeries("1.2.1", "1.2.1.1", "1.2.1.2"),
+			newReferencedSeries("1.2.2", "1.2.2.1")),
+		dicom.MustNewElement(dicomtag.PatientName, "Doe^John"),
+	}}
+	var paths []string
+	require.NoError(t, ds.Walk(func(path []dicom.PathStep, elem *dicom.Element) error {
+		paths = append(paths, dicom.Path(path).String())
+		return nil
+	}))
+	assert.Equal(t, []string{
+		"(0008,1115)",
+		"(0008,1115)[0]",
+		"(0008,1115)[0].(0008,114A)",
+		"(0008,1115)[0].(0008,114A)[0]",
+		"(0008,1115)[0].(0008,114A)[0].(0008,1155)",
+		"(0008,1115)[0].(0008,114A)[1]",
+		"(0008,1115)[0].(0008,114A)[1].(0008,1155)",
+		"(0008,1115)[0].(0020,000E)",
+		"(0008,1115)[1]",
+		"(0008,1115)[1].(0008,114A)",
+		"(0008,1115)[1].(0008,114A)[0]",
+		"(0008,1115)[1].(0008,114A)[0].(0008,1155)",
+		"(0008,1115)[1].(0020,000E)",
+		"(0010,0010)",
+	}, paths)
+
+	// The paths can be evaluated by Path.Find.
+	require.NoError(t, ds.Walk(func(path []dicom.PathStep, elem *dicom.Element) error {
+		elems, err := dicom.Path(path).Find(ds)
+		require.NoError(t, err)
+		assert.Equal(t, []*dicom.Element{elem}, elems)
+		return nil
+	}))
+
+	// Skip the first item of each sequence, and collect UIDs elsewhere.
+	var uids []string
+	require.NoError(t, ds.Walk(func(path []dicom.PathStep, elem *dicom.Element) error {
+		if elem.Tag == dicomtag.Item && path[len(path)-1].Index == 0 {
+			return dicom.SkipSubtree
+		}
+		if elem.VR == "UI" {
+			uids = append(uids, elem.MustGetString())
+		}
+		return nil
+	}))
+	assert.Equal(t, []string{"1.2.2"}, uids)
+
+	// Skip a whole sequence.
+	var tags []dicomtag.Tag
+	require.NoError(t, ds.Walk(func(path []dicom.PathStep, elem *dicom.Element) error {
+		tags = append(tags, elem.Tag)
+		if elem.Tag == dicomtag.ReferencedSeriesSequence {
+			return dicom.SkipSubtree
+		}
+		return nil
+	}))
+	assert.Equal(t, []dicomtag.Tag{dicomtag.ReferencedSeriesSequence, dicomtag.PatientName}, tags)
+
+	// An error stops the walk.
+	errStop := errors.New("stop")
+	n := 0
+	err := ds.Walk(func(path []dicom.PathStep, elem *dicom.Element) error {
+		n++
+		if elem.Tag == dicomtag.SeriesInstanceUID {
+			return errStop
+		}
+		return nil
+	})
+	assert.Equal(t, errStop, err)
+	assert.Equal(t, 8, n)
+}
+
+func TestWalkFile(t *testing.T) {
+	ds := mustReadFile(t, "examples/IM-0001-0001.dcm", dicom.ReadOptions{DropPixelData: true})
+	n := 0
+	require.NoError(t, ds.Walk(func(path []dicom.PathStep, elem *dicom.Element) error {
+		n++
+		return nil
+	}))
+	assert.True(t, n >= len(ds.Elements))
+}
+
+func TestWalkUNSequence(t *testing.T) {
+	// A UN element of undefined length, as read from a private sequence in
+	// implicit VR.
+	un := &dicom.Element{
+		Tag:             dicomtag.Tag{Group: 0x0009, Element: 0x1010},
+		VR:              "UN",
+		UndefinedLength: true,
+		Value: []interface{}{dicom.MustNewElement(dicomtag.Item,
+			dicom.MustNewElement(dicomtag.PatientName, "Doe^John"))},
+	}
+	ds := &dicom.DataSet{Elements: []*dicom.Element{un}}
+	var paths []string
+	require.NoError(t, ds.Walk(func(path []dicom.PathStep, elem *dicom.Element) error {
+		paths = append(paths, dicom.Path(path).String())
+		return nil
+	}))
+	assert.Equal(t, []string{"(0009,1010)", "(0009,1010)[0]", "(0009,1010)[0].(0010,0010)"}, paths)
+
+	elems, err := dicom.MustParsePath("(0009,1010)[0].PatientName").Find(ds)
+	require.NoError(t, err)
+	require.Len(t, elems, 1)
+	assert.Equal(t, "Doe^John", elems[0].MustGetString())
+}
@@ -1,23 +1,38 @@
 package dicom
 
 import (
+	"compress/flate"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"os"
 
 	"github.com/msz-kp/go-dicom/dicomio"
 	"github.com/msz-kp/go-dicom/dicomlog"
 	"github.com/msz-kp/go-dicom/dicomtag"
+	"github.com/msz-kp/go-dicom/dicomuid"
 )
 
 // WriteOptSet represents the flattened option set after all WriteOptions have been applied.
 type WriteOptSet struct {
 	SkipVRVerification bool
+	// StripPrivateTags drops private elements, including Private Creator
+	// elements, at every nesting level.
+	StripPrivateTags bool
+	// DeflateLevel is the compress/flate compression level used when the
+	// transfer syntax is Deflated Explicit VR Little Endian.
+	DeflateLevel int
+	// FallbackToUTF8 rewrites SpecificCharacterSet to ISO_IR 192 (UTF-8)
+	// when a string can't be encoded in the declared character set.
+	FallbackToUTF8 bool
+	// CP1250Fix encodes strings declared as ISO_IR 100 in windows-1250, as
+	// ReadOptions.CP1250Fix decodes them.
+	CP1250Fix bool
 }
 
 func toWriteOptSet(opts ...WriteOption) *WriteOptSet {
-	optSet := &WriteOptSet{}
+	optSet := &WriteOptSet{DeflateLevel: flate.DefaultCompression}
 	for _, opt := range opts {
 		opt(optSet)
 	}
@@ -35,6 +50,45 @@ func SkipVRVerification() WriteOption {
 	}
 }
 
+// StripPrivateTags returns a WriteOption that drops private elements (those
+// with an odd group number), including Private Creator elements. By default,
+// private elements are written.
+func StripPrivateTags() WriteOption {
+	return func(set *WriteOptSet) {
+		set.StripPrivateTags = true
+	}
+}
+
+// DeflateLevel returns a WriteOption that sets the compression level used for
+// the Deflated Explicit VR Little Endian transfer syntax. The level is one of
+// the compress/flate constants, e.g., flate.BestCompression. The default is
+// flate.DefaultCompression.
+func DeflateLevel(level int) WriteOption {
+	return func(set *WriteOptSet) {
+		set.DeflateLevel = level
+	}
+}
+
+// FallbackToUTF8 returns a WriteOption that writes a dataset or an item in
+// UTF-8 (ISO_IR 192) if one of its strings can't be encoded in the character
+// set declared by its SpecificCharacterSet, or if the declared character set
+// isn't supported. SpecificCharacterSet is rewritten accordingly. By default,
+// WriteDataSet fails on such a string.
+func FallbackToUTF8() WriteOption {
+	return func(set *WriteOptSet) {
+		set.FallbackToUTF8 = true
+	}
+}
+
+// CP1250Fix returns a WriteOption that encodes strings declared as ISO_IR 100
+// in windows-1250, the counterpart of ReadOptions.CP1250Fix. A dataset read
+// with that option must be written with this one to keep its strings intact.
+func CP1250Fix() WriteOption {
+	return func(set *WriteOptSet) {
+		set.CP1250Fix = true
+	}
+}
+
 // WriteFileHeader produces a DICOM file header. metaElems[] is be a list of
 // elements to be embedded in the header part.  Every element in metaElems[]
 // must have Tag.Group==2. It must contain at least the following three
@@ -47,7 +101,15 @@ func SkipVRVerification() WriteOption {
 // Consult the following page for the DICOM file header format.
 //
 // http://dicom.nema.org/dicom/2013/output/chtml/part10/chapter_7.html
+//
+// Deprecated: Use WriteFileMeta, which takes the same FileMeta struct that
+// ReadFileMeta returns. NewFileMeta converts meta elements to a FileMeta.
 func WriteFileHeader(e *dicomio.Encoder, metaElems []*Element, opts *WriteOptSet) {
+	writeFileHeader(e, metaElems, opts)
+}
+
+// writeFileHeader implements WriteFileHeader and WriteFileMeta.
+func writeFileHeader(e *dicomio.Encoder, metaElems []*Element, opts *WriteOptSet) {
 	e.PushTransferSyntax(binary.LittleEndian, dicomio.ExplicitVR)
 	defer e.PopTransferSyntax()
 
@@ -107,7 +169,7 @@ func encodeElementHeader(e *dicomio.Encoder, tag dicomtag.Tag, vr string, vl uin
 		doassert(len(vr) == 2, vr)
 		e.WriteString(vr)
 		switch vr {
-		case "NA", "OB", "OD", "OF", "OL", "OW", "SQ", "UN", "UC", "UR", "UT":
+		case "NA", "OB", "OD", "OF", "OL", "OV", "OW", "SQ", "SV", "UN", "UC", "UR", "UT", "UV":
 			e.WriteZeros(2) // two bytes for "future use" (0000H)
 			e.WriteUInt32(vl)
 		default:
@@ -133,7 +195,175 @@ func writeBasicOffsetTable(e *dicomio.Encoder, offsets []uint32) {
 	writeRawItem(e, subEncoder.Bytes())
 }
 
-func verifyVROrDefault(t dicomtag.Tag, vr string, opts *WriteOptSet) (string, error) {
+// writeEncapsulatedPixelData writes the frames of encapsulated pixel data, one
+// fragment per frame. Since that may not be how the frames were stored when
+// they were read, the basic offset table is computed from the frames. It's
+// left empty if there's only one frame, if an offset doesn't fit in 32 bits,
+// or if "basicOffsets" is false, which is required when the dataset has an
+// ExtendedOffsetTable. P3.5 A.4.
+func writeEncapsulatedPixelData(e *dicomio.Encoder, tag dicomtag.Tag, vr string, frames [][]byte, basicOffsets bool) {
+	encodeElementHeader(e, tag, vr, undefinedLength)
+	var offsets []uint32
+	if basicOffsets && len(frames) > 1 {
+		for _, offset := range fragmentOffsets(frames) {
+			if offset > math.MaxUint32 {
+				offsets = nil
+				break
+			}
+			offsets = append(offsets, uint32(offset))
+		}
+	}
+	writeBasicOffsetTable(e, offsets)
+	for _, frame := range frames {
+		writeRawItem(e, frame)
+	}
+	encodeElementHeader(e, dicomtag.SequenceDelimitationItem, "" /*not used*/, 0)
+}
+
+// selectElementsToWrite returns the subset of elems, which belong to one
+// dataset or item, that should be written. It drops private elements if
+// opts.StripPrivateTags is set. It also drops a private data element whose
+// Private Creator element is missing, since such an element can't be
+// interpreted by a reader.
+func selectElementsToWrite(elems []*Element, opts *WriteOptSet) []*Element {
+	creators := make(map[dicomtag.Tag]bool)
+	for _, elem := range elems {
+		if dicomtag.IsPrivateCreator(elem.Tag) {
+			creators[elem.Tag] = true
+		}
+	}
+	selected := make([]*Element, 0, len(elems))
+	for _, elem := range elems {
+		if dicomtag.IsPrivate(elem.Tag.Group) {
+			if opts.StripPrivateTags {
+				continue
+			}
+			if creator, ok := dicomtag.PrivateCreatorTag(elem.Tag); ok && !creators[creator] {
+				dicomlog.Vprintf(-1, "tag %s removed. (Private Creator %s not found)", elem.Tag, creator)
+				continue
+			}
+		}
+		selected = append(selected, elem)
+	}
+	return selected
+}
+
+// VRs whose values are encoded in the character set given by
+// SpecificCharacterSet. Values of other string VRs are restricted to the
+// default character repertoire. P3.5 6.1.2.3.
+var specificCharacterSetVRs = map[string]bool{
+	"SH": true, "LO": true, "UC": true, "ST": true, "LT": true, "UT": true, "PN": true,
+}
+
+// setCodingSystem sets the coding system of "e" to the one declared by the
+// SpecificCharacterSet element in elems, which belong to one dataset or item.
+// If elems lack the element, the coding system of "e" is left unchanged, since
+// an item inherits the character set of its enclosing dataset.
+//
+// If some string in elems can't be encoded, and either opts.FallbackToUTF8 is
+// set or no character set is in effect, it returns a copy of elems with
+// SpecificCharacterSet set to ISO_IR 192. Otherwise it returns elems.
+func setCodingSystem(e *dicomio.Encoder, elems []*Element, opts *WriteOptSet) []*Element {
+	charsetIndex := -1
+	for i, elem := range elems {
+		if elem.Tag == dicomtag.SpecificCharacterSet {
+			charsetIndex = i
+			break
+		}
+	}
+	if charsetIndex >= 0 {
+		names, err := elems[charsetIndex].GetStrings()
+		if err != nil {
+			e.SetError(err)
+			return elems
+		}
+		cs, err := dicomio.ParseSpecificCharacterSet(names, opts.CP1250Fix)
+		if err != nil {
+			e.SetError(err)
+			return elems
+		}
+		e.SetCodingSystem(cs)
+	}
+	// Without a character set, only ASCII can be written. Non-ASCII strings,
+	// e.g., those whose encoding the reader guessed, are written in UTF-8
+	// rather than failing.
+	undeclared := charsetIndex < 0 && e.CodingSystem() == dicomio.CodingSystem{}
+	if !(opts.FallbackToUTF8 || undeclared) || canEncodeElements(e, elems) {
+		return elems
+	}
+	cs, err := dicomio.ParseSpecificCharacterSet([]string{"ISO_IR 192"}, false)
+	if err != nil {
+		e.SetError(err)
+		return elems
+	}
+	e.SetCodingSystem(cs)
+	charset := MustNewElement(dicomtag.SpecificCharacterSet, "ISO_IR 192")
+	dicomlog.Vprintf(1, "dicom.WriteElement: Some strings can't be encoded in the declared character set. Writing them in %v", charset.Value[0])
+	newElems := make([]*Element, 0, len(elems)+1)
+	if charsetIndex >= 0 {
+		newElems = append(newElems, elems...)
+		newElems[charsetIndex] = charset
+		return newElems
+	}
+	inserted := false
+	for _, elem := range elems {
+		if !inserted && elem.Tag.Compare(dicomtag.SpecificCharacterSet) > 0 {
+			newElems = append(newElems, charset)
+			inserted = true
+		}
+		newElems = append(newElems, elem)
+	}
+	if !inserted {
+		newElems = append(newElems, charset)
+	}
+	return newElems
+}
+
+// canEncodeElements checks if every string in elems, including those in
+// sequence items that don't declare their own SpecificCharacterSet, can be
+// encoded by the current coding system of "e".
+func canEncodeElements(e *dicomio.Encoder, elems []*Element) bool {
+	for _, elem := range elems {
+		vr := elem.VR
+		if vr == "" {
+			if info, err := dicomtag.Find(elem.Tag); err == nil {
+				vr = info.VR
+			}
+		}
+		for _, value := range elem.Value {
+			switch v := value.(type) {
+			case string:
+				if specificCharacterSetVRs[vr] {
+					if _, err := e.EncodeString(v); err != nil {
+						return false
+					}
+				}
+			case *Element:
+				if v.Tag != dicomtag.Item {
+					continue
+				}
+				items := v.GetElements()
+				if _, err := FindElementByTag(items, dicomtag.SpecificCharacterSet); err == nil {
+					continue
+				}
+				if !canEncodeElements(e, items) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// newSubEncoder creates an in-memory encoder that inherits the transfer syntax
+// and the coding system of "e".
+func newSubEncoder(e *dicomio.Encoder) *dicomio.Encoder {
+	sube := dicomio.NewBytesEncoder(e.TransferSyntax())
+	sube.SetCodingSystem(e.CodingSystem())
+	return sube
+}
+
+func verifyVROrDefault(t dicomtag.Tag, vr string, creator string, opts *WriteOptSet) (string, error) {
 	if vr != "" && opts.SkipVRVerification {
 		return vr, nil
 	}
@@ -142,6 +372,11 @@ func verifyVROrDefault(t dicomtag.Tag, vr string, opts *WriteOptSet) (string, er
 	if err != nil {
 		if vr == "" {
 			vr = "UN"
+			if info, err := dicomtag.FindPrivate(t, creator); err == nil {
+				vr = info.VR
+			} else if dicomtag.IsPrivateCreator(t) {
+				vr = "LO"
+			}
 		}
 		return vr, nil
 	}
@@ -168,7 +403,7 @@ func verifyVROrDefault(t dicomtag.Tag, vr string, opts *WriteOptSet) (string, er
 // is for UL, then each value must be uint32.
 
 func WriteElement(e *dicomio.Encoder, elem *Element, opts *WriteOptSet) {
-	vr, err := verifyVROrDefault(elem.Tag, elem.VR, opts)
+	vr, err := verifyVROrDefault(elem.Tag, elem.VR, elem.PrivateCreator, opts)
 	if err != nil {
 		e.SetErrorf(err.Error())
 		return
@@ -184,12 +419,7 @@ func WriteElement(e *dicomio.Encoder, elem *Element, opts *WriteOptSet) {
 			e.SetError(fmt.Errorf("PixelData element must have one value of type PixelDataInfo"))
 		}
 		if elem.UndefinedLength {
-			encodeElementHeader(e, elem.Tag, vr, undefinedLength)
-			writeBasicOffsetTable(e, image.Offsets)
-			for _, image := range image.Frames {
-				writeRawItem(e, image)
-			}
-			encodeElementHeader(e, dicomtag.SequenceDelimitationItem, "" /*not used*/, 0)
+			writeEncapsulatedPixelData(e, elem.Tag, vr, image.Frames, true)
 		} else {
 			doassert(len(image.Frames) == 1, image.Frames) // TODO
 			encodeElementHeader(e, elem.Tag, vr, uint32(len(image.Frames[0])))
@@ -197,9 +427,15 @@ func WriteElement(e *dicomio.Encoder, elem *Element, opts *WriteOptSet) {
 		}
 		return
 	}
-	if vr == "SQ" {
+	if vr == "SQ" || (vr == "UN" && elem.UndefinedLength) {
 		if elem.UndefinedLength {
 			encodeElementHeader(e, elem.Tag, vr, undefinedLength)
+			if vr == "UN" {
+				// The items are encoded in implicit little endian
+				// regardless of the transfer syntax. PS3.5 6.2.2.
+				e.PushTransferSyntax(binary.LittleEndian, dicomio.ImplicitVR)
+				defer e.PopTransferSyntax()
+			}
 			for _, value := range elem.Value {
 				subelem, ok := value.(*Element)
 				if !ok || subelem.Tag != dicomtag.Item {
@@ -210,7 +446,7 @@ func WriteElement(e *dicomio.Encoder, elem *Element, opts *WriteOptSet) {
 			}
 			encodeElementHeader(e, dicomtag.SequenceDelimitationItem, "" /*not used*/, 0)
 		} else {
-			sube := dicomio.NewBytesEncoder(e.TransferSyntax())
+			sube := newSubEncoder(e)
 			for _, value := range elem.Value {
 				subelem, ok := value.(*Element)
 				if !ok || subelem.Tag != dicomtag.Item {
@@ -228,25 +464,28 @@ func WriteElement(e *dicomio.Encoder, elem *Element, opts *WriteOptSet) {
 			e.WriteBytes(bytes)
 		}
 	} else if vr == "NA" { // Item
+		subelems := make([]*Element, 0, len(elem.Value))
+		for _, value := range elem.Value {
+			subelem, ok := value.(*Element)
+			if !ok {
+				e.SetErrorf("Item values must be a dicom.Element, but found %v", value)
+				return
+			}
+			subelems = append(subelems, subelem)
+		}
+		// SpecificCharacterSet in an item applies only to the item.
+		e.PushCodingSystem()
+		defer e.PopCodingSystem()
+		subelems = setCodingSystem(e, selectElementsToWrite(subelems, opts), opts)
 		if elem.UndefinedLength {
 			encodeElementHeader(e, elem.Tag, vr, undefinedLength)
-			for _, value := range elem.Value {
-				subelem, ok := value.(*Element)
-				if !ok {
-					e.SetErrorf("Item values must be a dicom.Element, but found %v", value)
-					return
-				}
+			for _, subelem := range subelems {
 				WriteElement(e, subelem, opts)
 			}
 			encodeElementHeader(e, dicomtag.ItemDelimitationItem, "" /*not used*/, 0)
 		} else {
-			sube := dicomio.NewBytesEncoder(e.TransferSyntax())
-			for _, value := range elem.Value {
-				subelem, ok := value.(*Element)
-				if !ok {
-					e.SetErrorf("Item values must be a dicom.Element, but found %v", value)
-					return
-				}
+			sube := newSubEncoder(e)
+			for _, subelem := range subelems {
 				WriteElement(sube, subelem, opts)
 			}
 			if sube.Error() != nil {
@@ -258,10 +497,9 @@ func WriteElement(e *dicomio.Encoder, elem *Element, opts *WriteOptSet) {
 			e.WriteBytes(bytes)
 		}
 	} else {
-		if elem.UndefinedLength && vr != "UN" {
+		if elem.UndefinedLength {
 			e.SetErrorf("Encoding undefined-length element not yet supported: %v", elem)
 			return
-
 		}
 		sube := dicomio.NewBytesEncoder(e.TransferSyntax())
 		switch vr {
@@ -275,7 +513,7 @@ func WriteElement(e *dicomio.Encoder, elem *Element, opts *WriteOptSet) {
 				}
 				sube.WriteUInt16(v)
 			}
-		case "UL":
+		case "UL", "OL":
 			for _, value := range elem.Value {
 				v, ok := value.(uint32)
 				if !ok {
@@ -285,6 +523,26 @@ func WriteElement(e *dicomio.Encoder, elem *Element, opts *WriteOptSet) {
 				}
 				sube.WriteUInt32(v)
 			}
+		case "UV", "OV":
+			for _, value := range elem.Value {
+				v, ok := value.(uint64)
+				if !ok {
+					e.SetErrorf("%v: expect uint64, but found %v",
+						dicomtag.DebugString(elem.Tag), value)
+					continue
+				}
+				sube.WriteUInt64(v)
+			}
+		case "SV":
+			for _, value := range elem.Value {
+				v, ok := value.(int64)
+				if !ok {
+					e.SetErrorf("%v: expect int64, but found %v",
+						dicomtag.DebugString(elem.Tag), value)
+					continue
+				}
+				sube.WriteInt64(v)
+			}
 		case "SL":
 			for _, value := range elem.Value {
 				v, ok := value.(int32)
@@ -329,7 +587,7 @@ func WriteElement(e *dicomio.Encoder, elem *Element, opts *WriteOptSet) {
 				}
 				sube.WriteFloat64(v)
 			}
-		case "OW", "OB": // TODO(saito) Check that size is even. Byte swap??
+		case "OW", "OB", "UN": // TODO(saito) Check that size is even. Byte swap??
 			if len(elem.Value) != 1 {
 				e.SetErrorf("%v: expect a single value but found %v",
 					dicomtag.DebugString(elem.Tag), elem.Value)
@@ -354,7 +612,7 @@ func WriteElement(e *dicomio.Encoder, elem *Element, opts *WriteOptSet) {
 					sube.WriteUInt16(v)
 				}
 				doassert(d.Finish() == nil, d.Error())
-			} else { // vr=="OB"
+			} else { // vr=="OB" or "UN"
 				sube.WriteBytes(bytes)
 				if len(bytes)%2 == 1 {
 					sube.WriteByte(0)
@@ -386,15 +644,20 @@ func WriteElement(e *dicomio.Encoder, elem *Element, opts *WriteOptSet) {
 			if len(s)%2 == 1 {
 				sube.WriteByte(0)
 			}
-		case "AT", "NA":
+		case "AT":
+			for _, value := range elem.Value {
+				v, ok := value.(dicomtag.Tag)
+				if !ok {
+					e.SetErrorf("%v: expect dicomtag.Tag, but found %v",
+						dicomtag.DebugString(elem.Tag), value)
+					continue
+				}
+				sube.WriteUInt16(v.Group)
+				sube.WriteUInt16(v.Element)
+			}
+		case "NA":
 			fallthrough
 		default:
-			// dirty UN with undef len hack
-			if elem.UndefinedLength && vr == "UN" {
-				dicomlog.Vprintf(-1, "tag %s removed. (vr 'UN' with undefined length unsupported)", elem.Tag)
-				break
-			}
-
 			s := ""
 			for i, value := range elem.Value {
 				var substr string
@@ -416,8 +679,20 @@ func WriteElement(e *dicomio.Encoder, elem *Element, opts *WriteOptSet) {
 				}
 				s += substr
 			}
-			sube.WriteString(s)
-			if len(s)%2 == 1 {
+			if !specificCharacterSetVRs[vr] {
+				sube.WriteString(s)
+				if len(s)%2 == 1 {
+					sube.WriteByte(' ')
+				}
+				break
+			}
+			bytes, err := e.EncodeString(s)
+			if err != nil {
+				e.SetErrorf("%v: %v", dicomtag.DebugString(elem.Tag), err)
+				break
+			}
+			sube.WriteBytes(bytes)
+			if len(bytes)%2 == 1 {
 				sube.WriteByte(' ')
 			}
 		}
@@ -436,36 +711,114 @@ func WriteElement(e *dicomio.Encoder, elem *Element, opts *WriteOptSet) {
 //
 // The transfer syntax (byte order, etc) of the file is determined by the
 // TransferSyntax element in "ds". If ds is missing that or a few other
-// essential elements, this function returns an error.
+// essential elements, this function returns an error. If the transfer syntax
+// is Deflated Explicit VR Little Endian, the elements that follow the
+// metadata are compressed. Use DeflateLevel to choose the compression level.
+// If the transfer syntax is RLE Lossless and the PixelData element holds
+// native pixel data, which must be little endian, each frame is compressed
+// into one fragment. Encapsulated pixel data is written as is, whatever the
+// transfer syntax; it is never decoded, so the caller must make sure that it
+// matches the transfer syntax.
+//
+// Encapsulated pixel data is written one fragment per frame, and the basic
+// offset table, or ExtendedOffsetTable and ExtendedOffsetTableLengths if the
+// dataset has them, are recomputed to match.
+//
+// Strings are encoded in the character set declared by SpecificCharacterSet.
+// If a string can't be encoded, WriteDataSet returns an error, unless
+// FallbackToUTF8 is given. If the element is missing, strings are written in
+// 7bit ASCII, or if some aren't ASCII, in UTF-8, and SpecificCharacterSet is
+// added.
 //
 //	ds := ... read or create dicom.Dataset ...
 //	out, err := os.Create("test.dcm")
 //	err := dicom.Write(out, ds)
 func WriteDataSet(out io.Writer, ds *DataSet, opts ...WriteOption) error {
 	optSet := toWriteOptSet(opts...)
-	e := dicomio.NewEncoder(out, nil, dicomio.UnknownVR)
-	var metaElems []*Element
-	for _, elem := range ds.Elements {
-		if elem.Tag.Group == dicomtag.MetadataGroup {
-			metaElems = append(metaElems, elem)
+	// Everything that may fail is checked before anything is written, so
+	// that an error doesn't leave a truncated file behind.
+	endian, implicit, err := getTransferSyntax(ds)
+	if err != nil {
+		return err
+	}
+	uid, _ := getTransferSyntaxUID(ds)
+	var frames [][]byte // The frames of encapsulated pixel data, if any.
+	pixelData, err := ds.FindElementByTag(dicomtag.PixelData)
+	if err == nil {
+		if !pixelData.UndefinedLength && uid == dicomuid.RLELossless {
+			if pixelData, err = encodeRLEPixelData(ds, pixelData); err != nil {
+				return err
+			}
 		}
+		frames = encapsulatedFrames(pixelData)
 	}
-	WriteFileHeader(e, metaElems, optSet)
+	hasExtendedOffsets := ds.Has(dicomtag.ExtendedOffsetTable)
+	var pixelDataVR string
+	if frames != nil && hasExtendedOffsets {
+		if pixelDataVR, err = verifyVROrDefault(pixelData.Tag, pixelData.VR, pixelData.PrivateCreator, optSet); err != nil {
+			return err
+		}
+	}
+	var zw *flate.Writer
+	if uid == dicomuid.DeflatedExplicitVRLittleEndian {
+		// The file meta group stays uncompressed, but everything after
+		// it is compressed. PS3.5 A.5. NewWriter writes nothing, but it
+		// checks the level.
+		if zw, err = flate.NewWriter(out, optSet.DeflateLevel); err != nil {
+			return fmt.Errorf("dicom.WriteDataSet: %v", err)
+		}
+	}
+
+	meta, err := NewFileMeta(ds.Elements)
+	if err != nil {
+		return fmt.Errorf("dicom.WriteDataSet: %v", err)
+	}
+
+	e := dicomio.NewEncoder(out, nil, dicomio.UnknownVR)
+	WriteFileMeta(e, meta, optSet)
 	if e.Error() != nil {
 		return e.Error()
 	}
-	endian, implicit, err := getTransferSyntax(ds)
-	if err != nil {
-		return err
+	if zw != nil {
+		e = dicomio.NewEncoder(zw, nil, dicomio.UnknownVR)
 	}
 	e.PushTransferSyntax(endian, implicit)
-	for _, elem := range ds.Elements {
-		// Пропускаем приватные теги (нечетная группа) и метаданные
-		if elem.Tag.Group != dicomtag.MetadataGroup && elem.Tag.Group%2 == 0 {
-			WriteElement(e, elem, optSet)
+	for _, elem := range setCodingSystem(e, selectElementsToWrite(ds.Elements, optSet), optSet) {
+		switch {
+		case elem.Tag.Group == dicomtag.MetadataGroup:
+			continue
+		case elem.Tag == dicomtag.ExtendedOffsetTable || elem.Tag == dicomtag.ExtendedOffsetTableLengths:
+			// The frames are written one fragment per frame, so the
+			// table is recomputed, or dropped if the pixel data isn't
+			// encapsulated.
+			if frames == nil {
+				dicomlog.Vprintf(1, "dicom.WriteDataSet: %v dropped, since the pixel data isn't encapsulated",
+					dicomtag.DebugString(elem.Tag))
+				continue
+			}
+			offsets, lengths := extendedOffsetTable(frames)
+			if elem.Tag == dicomtag.ExtendedOffsetTable {
+				elem = offsets
+			} else {
+				elem = lengths
+			}
+		case elem.Tag == dicomtag.PixelData:
+			elem = pixelData
+			if frames != nil && hasExtendedOffsets {
+				writeEncapsulatedPixelData(e, elem.Tag, pixelDataVR, frames, false)
+				continue
+			}
 		}
+		WriteElement(e, elem, optSet)
 	}
 	e.PopTransferSyntax()
+	if zw != nil {
+		// Close the stream even if an element failed, so that the
+		// compressor is flushed and released.
+		if err := zw.Close(); err != nil {
+			e.SetError(err)
+		}
+	}
 	return e.Error()
 }
 